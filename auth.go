@@ -0,0 +1,218 @@
+package grabanaclistarter
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/K-Phoen/grabana"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	CliApiKeyFile        CliValues = "apikey-file"
+	CliBasicAuthUser     CliValues = "basic-auth-user"
+	CliBasicAuthPassword CliValues = "basic-auth-password"
+	CliCaCert            CliValues = "ca-cert"
+	CliClientCert        CliValues = "client-cert"
+	CliClientKey         CliValues = "client-key"
+	CliProxy             CliValues = "proxy"
+	CliHeader            CliValues = "header"
+)
+
+func authFlags(appName string) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    CliApiKey,
+			Aliases: []string{"token"},
+			EnvVars: []string{GetFlagEnvByFlagName(CliApiKey, appName)},
+			Usage:   "grafana api key or service account token (bearer)",
+		},
+		&cli.StringFlag{
+			Name:    CliApiKeyFile,
+			EnvVars: []string{GetFlagEnvByFlagName(CliApiKeyFile, appName)},
+			Usage:   "read the api key or service account token from file (- for stdin)",
+		},
+		&cli.StringFlag{
+			Name:    CliBasicAuthUser,
+			EnvVars: []string{GetFlagEnvByFlagName(CliBasicAuthUser, appName)},
+			Usage:   "grafana user for basic auth",
+		},
+		&cli.StringFlag{
+			Name:    CliBasicAuthPassword,
+			EnvVars: []string{GetFlagEnvByFlagName(CliBasicAuthPassword, appName)},
+			Usage:   "grafana password for basic auth",
+		},
+		&cli.StringFlag{
+			Name:    CliCaCert,
+			EnvVars: []string{GetFlagEnvByFlagName(CliCaCert, appName)},
+			Usage:   "pem ca bundle to trust additionally to the system pool",
+		},
+		&cli.StringFlag{
+			Name:    CliClientCert,
+			EnvVars: []string{GetFlagEnvByFlagName(CliClientCert, appName)},
+			Usage:   "pem client certificate for mTLS",
+		},
+		&cli.StringFlag{
+			Name:    CliClientKey,
+			EnvVars: []string{GetFlagEnvByFlagName(CliClientKey, appName)},
+			Usage:   "pem client key for mTLS",
+		},
+		&cli.StringFlag{
+			Name:    CliProxy,
+			EnvVars: []string{GetFlagEnvByFlagName(CliProxy, appName)},
+			Usage:   "http proxy url (default from HTTP_PROXY/HTTPS_PROXY)",
+		},
+		&cli.StringSliceFlag{
+			Name:    CliHeader,
+			EnvVars: []string{GetFlagEnvByFlagName(CliHeader, appName)},
+			Usage:   "extra header send with every request as key=value (repeatable)",
+		},
+	}
+}
+
+// authTransport adds authentication and extra headers to every request
+type authTransport struct {
+	base     http.RoundTripper
+	token    string
+	user     string
+	password string
+	headers  http.Header
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for k, v := range t.headers {
+		req.Header[k] = v
+	}
+	switch {
+	case t.token != "":
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	case t.user != "":
+		req.SetBasicAuth(t.user, t.password)
+	}
+	return t.base.RoundTrip(req)
+}
+
+func readToken(file string) (string, error) {
+	var content []byte
+	var err error
+	if file == "-" {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return "", fmt.Errorf("Error reading token: %w", err)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+func tlsConfig(c *cli.Context) (*tls.Config, error) {
+	config := &tls.Config{}
+	if caFile := c.String(CliCaCert); caFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		ca, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading ca bundle: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("No certificate found in %s", caFile)
+		}
+		config.RootCAs = pool
+	}
+	certFile, keyFile := c.String(CliClientCert), c.String(CliClientKey)
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, fmt.Errorf("--%s and --%s have to be used together", CliClientCert, CliClientKey)
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Error loading client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// checkAuthModes rejects more than one way to authenticate, none of them would win silently
+func checkAuthModes(c *cli.Context) error {
+	modes := []string{}
+	for _, name := range []string{CliApiKey, CliApiKeyFile, CliBasicAuthUser} {
+		if c.String(name) != "" {
+			modes = append(modes, "--"+name)
+		}
+	}
+	if len(modes) > 1 {
+		return fmt.Errorf("Only one of %s can be used", strings.Join(modes, ", "))
+	}
+	return nil
+}
+
+// newAuthHTTPClient builds the http client used for every grafana request. Without requireAuth no credentials are needed
+func newAuthHTTPClient(c *cli.Context, requireAuth bool, logger *slog.Logger) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	tlsConf, err := tlsConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConf
+	if proxy := c.String(CliProxy); proxy != "" {
+		proxyUrl, err := url.Parse(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid proxy url: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyUrl)
+	}
+
+	if err := checkAuthModes(c); err != nil {
+		return nil, err
+	}
+	auth := &authTransport{
+		base:     transport,
+		token:    c.String(CliApiKey),
+		user:     c.String(CliBasicAuthUser),
+		password: c.String(CliBasicAuthPassword),
+		headers:  http.Header{},
+	}
+	if file := c.String(CliApiKeyFile); file != "" {
+		if auth.token, err = readToken(file); err != nil {
+			return nil, err
+		}
+	}
+	if requireAuth && auth.token == "" && auth.user == "" {
+		return nil, fmt.Errorf("One of --%s, --%s or --%s is required", CliApiKey, CliApiKeyFile, CliBasicAuthUser)
+	}
+	for _, kv := range c.StringSlice(CliHeader) {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, fmt.Errorf("Invalid --%s %q, expected key=value", CliHeader, kv)
+		}
+		auth.headers.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+	}
+
+	client := newHTTPClient(c)
+	client.Transport = &loggingTransport{base: auth, logger: logger}
+	return client, nil
+}
+
+// newClient sets Server, HTTPClient and Client out of the cli flags
+func (r *Runner) newClient(c *cli.Context, requireAuth bool) error {
+	httpClient, err := newAuthHTTPClient(c, requireAuth, r.logger())
+	if err != nil {
+		return err
+	}
+	r.Server = c.String(CliServer)
+	r.HTTPClient = httpClient
+	r.Client = grabana.NewClient(httpClient, r.Server)
+	return nil
+}
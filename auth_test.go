@@ -0,0 +1,87 @@
+package grabanaclistarter
+
+import (
+	"flag"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+// flagContext parses args with flags into a cli.Context
+func flagContext(t *testing.T, flags []cli.Flag, args ...string) *cli.Context {
+	t.Helper()
+	set := flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+	for _, f := range flags {
+		if err := f.Apply(set); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := set.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return cli.NewContext(&cli.App{Flags: flags}, set, nil)
+}
+
+func TestAuthModes(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+		want    http.Header
+	}{
+		{name: "token", args: []string{"--apikey", "secret"}, want: http.Header{"Authorization": {"Bearer secret"}}},
+		{name: "basic auth", args: []string{"--basic-auth-user", "u", "--basic-auth-password", "p"}, want: http.Header{"Authorization": {"Basic dTpw"}}},
+		{name: "header", args: []string{"--apikey", "secret", "--header", "X-Scope=team"}, want: http.Header{"X-Scope": {"team"}}},
+		{name: "token and basic auth", args: []string{"--apikey", "secret", "--basic-auth-user", "u"}, wantErr: true},
+		{name: "token and token file", args: []string{"--apikey", "secret", "--apikey-file", "token.txt"}, wantErr: true},
+		{name: "none", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := newAuthHTTPClient(flagContext(t, append(authFlags("t"), timeoutFlags("t")...), tt.args...), true, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := http.Header{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Clone()
+			}))
+			defer server.Close()
+			if _, err := client.Get(server.URL); err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tt.want {
+				if got.Get(k) != v[0] {
+					t.Errorf("header %s is %q, want %q", k, got.Get(k), v[0])
+				}
+			}
+		})
+	}
+}
+
+func TestMaskConfigValue(t *testing.T) {
+	tests := []struct {
+		name, value, want string
+	}{
+		{name: CliApiKey, value: "secret", want: "****"},
+		{name: CliBasicAuthPassword, value: "", want: ""},
+		{name: CliHeader, value: `"Authorization=Bearer x", "X-Scope=team"`, want: `"Authorization=****", "X-Scope=****"`},
+		{name: CliHeader, value: "Authorization=Bearer x,X-Scope=team", want: "Authorization=****,X-Scope=****"},
+		{name: CliServer, value: "http://grafana", want: "http://grafana"},
+	}
+	for _, tt := range tests {
+		if got := maskConfigValue(tt.name, tt.value); got != tt.want {
+			t.Errorf("maskConfigValue(%s, %s) = %s, want %s", tt.name, tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package grabanaclistarter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	CliPrometheus CliValues = "prometheus"
+	CliMaxSeries  CliValues = "max-series"
+	CliQueryRange CliValues = "query-range"
+)
+
+func checkQueriesFlags(appName string) []cli.Flag {
+	return append(append(creatorFlags(appName), filterFlags(appName)...),
+		&cli.StringFlag{
+			Name:     CliPrometheus,
+			EnvVars:  []string{GetFlagEnvByFlagName(CliPrometheus, appName)},
+			Required: true,
+			Usage:    "url of the prometheus to run the queries against",
+		},
+		&cli.IntFlag{
+			Name:    CliMaxSeries,
+			EnvVars: []string{GetFlagEnvByFlagName(CliMaxSeries, appName)},
+			Value:   500,
+			Usage:   "report queries returning more series",
+		},
+		&cli.DurationFlag{
+			Name:    CliQueryRange,
+			EnvVars: []string{GetFlagEnvByFlagName(CliQueryRange, appName)},
+			Value:   15 * time.Minute,
+			Usage:   "time range of the range query",
+		},
+		&cli.DurationFlag{
+			Name:    CliRequestTimeout,
+			EnvVars: []string{GetFlagEnvByFlagName(CliRequestTimeout, appName)},
+			Value:   30 * time.Second,
+			Usage:   "timeout of every single prometheus request (0 = none)",
+		},
+	)
+}
+
+type QueryState string
+
+const (
+	QueryOK         QueryState = "ok"
+	QueryEmpty      QueryState = "empty"
+	QueryHighSeries QueryState = "high-series"
+	QueryError      QueryState = "error"
+)
+
+// QueryCheck is the result of one target executed as instant or range query
+type QueryCheck struct {
+	Dashboard string     `json:"dashboard" yaml:"dashboard"`
+	Panel     string     `json:"panel" yaml:"panel"`
+	RefID     string     `json:"refId" yaml:"refId"`
+	Kind      string     `json:"kind" yaml:"kind"`
+	Query     string     `json:"query" yaml:"query"`
+	State     QueryState `json:"state" yaml:"state"`
+	Series    int        `json:"series" yaml:"series"`
+	Error     string     `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+type CheckQueriesOptions struct {
+	CreatorInput
+	Filter Filter
+	// Prometheus is the base url of the prometheus api
+	Prometheus string
+	// Client is used for all requests, http.DefaultClient if nil
+	Client *http.Client
+	// MaxSeries marks results with more series as QueryHighSeries (0 = no limit)
+	MaxSeries int
+	// Range of the range query, the step is Range/30
+	Range time.Duration
+}
+
+// queryVariables resolves the dashboard variables to their default values and the grafana interval variables
+// to values matching rng and step. Multiple values are joined as regex alternative, "All" as .*
+func queryVariables(board lintBoard, rng, step time.Duration) map[string]string {
+	res := map[string]string{
+		"__interval":      model.Duration(step).String(),
+		"__interval_ms":   strconv.FormatInt(step.Milliseconds(), 10),
+		"__rate_interval": model.Duration(max(4*step, time.Minute)).String(),
+		"__range":         model.Duration(rng).String(),
+		"__range_s":       strconv.FormatInt(int64(rng.Seconds()), 10),
+		"__range_ms":      strconv.FormatInt(rng.Milliseconds(), 10),
+		"__dashboard":     board.UID,
+	}
+	for _, v := range board.Templating.List {
+		values := []string{}
+		switch current := v.Current.Value.(type) {
+		case string:
+			values = append(values, current)
+		case []any:
+			for _, c := range current {
+				if s, ok := c.(string); ok {
+					values = append(values, s)
+				}
+			}
+		}
+		for i, value := range values {
+			if value == "$__all" {
+				values[i] = ".*"
+			}
+		}
+		if v.Type == "interval" && len(values) == 1 && values[0] == "auto" {
+			values[0] = res["__interval"]
+		}
+		if len(values) > 0 {
+			res[v.Name] = strings.Join(values, "|")
+		}
+	}
+	return res
+}
+
+// resolveVariables replaces all known variables of query. Unknown variables are returned
+func resolveVariables(query string, values map[string]string) (string, []string) {
+	unknown := []string{}
+	var b strings.Builder
+	last := 0
+	for _, m := range variableMatches(query) {
+		b.WriteString(query[last:m.Start])
+		if v, ok := values[m.Name]; ok {
+			b.WriteString(v)
+		} else {
+			unknown = append(unknown, m.Name)
+			b.WriteString(query[m.Start:m.End])
+		}
+		last = m.End
+	}
+	b.WriteString(query[last:])
+	return b.String(), unknown
+}
+
+type prometheusResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// prometheusQuery runs the query at the prometheus api path and returns the number of series
+func prometheusQuery(ctx context.Context, client *http.Client, server, path string, params url.Values) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(server, "/")+path, strings.NewReader(params.Encode()))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	var res prometheusResponse
+	if err := json.Unmarshal(content, &res); err != nil {
+		return 0, fmt.Errorf("could not query prometheus: %s (HTTP status %d)", content, resp.StatusCode)
+	}
+	if res.Status != "success" {
+		return 0, fmt.Errorf("%s: %s", res.ErrorType, res.Error)
+	}
+	switch res.Data.ResultType {
+	case "vector", "matrix":
+		series := []json.RawMessage{}
+		if err := json.Unmarshal(res.Data.Result, &series); err != nil {
+			return 0, err
+		}
+		return len(series), nil
+	}
+	return 1, nil
+}
+
+func queryCheck(check QueryCheck, series, maxSeries int, err error) QueryCheck {
+	check.Series = series
+	switch {
+	case err != nil:
+		check.State = QueryError
+		check.Error = err.Error()
+	case series == 0:
+		check.State = QueryEmpty
+	case maxSeries > 0 && series > maxSeries:
+		check.State = QueryHighSeries
+	default:
+		check.State = QueryOK
+	}
+	return check
+}
+
+// CheckQueries runs every prometheus target of the dashboards as instant and range query against
+// CheckQueriesOptions.Prometheus
+func (r *Runner) CheckQueries(ctx context.Context, in CheckQueriesOptions) ([]QueryCheck, error) {
+	board, err := r.Dashboards(in.CreatorInput, in.Filter)
+	if err != nil {
+		return nil, err
+	}
+	client := in.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	rng := in.Range
+	if rng <= 0 {
+		rng = 15 * time.Minute
+	}
+	step := max(rng/30, time.Second)
+	res := []QueryCheck{}
+	for _, b := range board {
+		lb, err := parseLintBoard(b)
+		if err != nil {
+			return res, err
+		}
+		values := queryVariables(lb, rng, step)
+		for _, p := range lb.panels() {
+			for i, t := range p.Targets {
+				if !isPrometheusTarget(p, t) {
+					continue
+				}
+				check := QueryCheck{Dashboard: lb.UID, Panel: p.Title, RefID: fmt.Sprintf("#%d", i)}
+				if refID, _ := t["refId"].(string); refID != "" {
+					check.RefID = refID
+				}
+				query, unknown := resolveVariables(t["expr"].(string), values)
+				check.Query = query
+				if len(unknown) > 0 {
+					check.Kind = "instant"
+					res = append(res, queryCheck(check, 0, 0, fmt.Errorf("no default value for variables %s", strings.Join(unknown, ", "))))
+					continue
+				}
+				if ctx.Err() != nil {
+					return res, ctx.Err()
+				}
+				now := time.Now()
+				check.Kind = "instant"
+				series, err := prometheusQuery(ctx, client, in.Prometheus, "/api/v1/query", url.Values{
+					"query": {query},
+					"time":  {strconv.FormatInt(now.Unix(), 10)},
+				})
+				res = append(res, queryCheck(check, series, in.MaxSeries, err))
+				check.Kind = "range"
+				series, err = prometheusQuery(ctx, client, in.Prometheus, "/api/v1/query_range", url.Values{
+					"query": {query},
+					"start": {strconv.FormatInt(now.Add(-rng).Unix(), 10)},
+					"end":   {strconv.FormatInt(now.Unix(), 10)},
+					"step":  {strconv.FormatFloat(step.Seconds(), 'f', -1, 64)},
+				})
+				res = append(res, queryCheck(check, series, in.MaxSeries, err))
+			}
+		}
+	}
+	return res, nil
+}
+
+func (r *Runner) checkQueriesAction(c *cli.Context) error {
+	checks, err := r.CheckQueries(r.Ctx, CheckQueriesOptions{
+		CreatorInput: r.CreatorInput(c),
+		Filter:       FilterFromValues(c),
+		Prometheus:   c.String(CliPrometheus),
+		Client:       newHTTPClient(c),
+		MaxSeries:    c.Int(CliMaxSeries),
+		Range:        c.Duration(CliQueryRange),
+	})
+	if format := c.String(CliOutput); format != OutputText {
+		err = errors.Join(err, writeStructured(os.Stdout, format, checks))
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "DASHBOARD\tPANEL\tREFID\tKIND\tSTATE\tSERIES\tERROR")
+		for _, ch := range checks {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n", ch.Dashboard, ch.Panel, ch.RefID, ch.Kind, ch.State, ch.Series, ch.Error)
+		}
+		err = errors.Join(err, w.Flush())
+	}
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, ch := range checks {
+		if ch.State == QueryError {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return cli.Exit(fmt.Sprintf("%d queries failed", failed), 1)
+	}
+	return nil
+}
+
+func (r *Runner) beforeCheck(c *cli.Context) error {
+	r.withTimeout(c)
+	return r.withEnvironment(c)
+}
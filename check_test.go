@@ -0,0 +1,250 @@
+package grabanaclistarter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/K-Phoen/grabana/dashboard"
+	"github.com/K-Phoen/grabana/row"
+	"github.com/K-Phoen/grabana/timeseries"
+	"github.com/K-Phoen/grabana/variable/custom"
+	"github.com/K-Phoen/grabana/variable/interval"
+)
+
+// fakePrometheus answers instant and range queries with as many series as the query names:
+// "ok" 2, "empty" 0, "many" 5 and "bad" a bad_data error
+type fakePrometheus struct {
+	*httptest.Server
+	mu      sync.Mutex
+	queries map[string][]string
+}
+
+func newFakePrometheus(t *testing.T) *fakePrometheus {
+	t.Helper()
+	f := &fakePrometheus{queries: map[string][]string{}}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if err := req.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		query := req.PostForm.Get("query")
+		f.mu.Lock()
+		f.queries[req.URL.Path] = append(f.queries[req.URL.Path], query)
+		f.mu.Unlock()
+		resultType := "vector"
+		if req.URL.Path == "/api/v1/query_range" {
+			resultType = "matrix"
+		} else if req.URL.Path != "/api/v1/query" {
+			http.NotFound(w, req)
+			return
+		}
+		series := 0
+		switch {
+		case strings.Contains(query, "bad"):
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"status": "error", "errorType": "bad_data", "error": "parse error"})
+			return
+		case strings.Contains(query, "many"):
+			series = 5
+		case strings.Contains(query, "ok"):
+			series = 2
+		}
+		result := make([]map[string]any, series)
+		for i := range result {
+			result[i] = map[string]any{"metric": map[string]string{}, "value": []any{0, "1"}}
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"status": "success",
+			"data":   map[string]any{"resultType": resultType, "result": result},
+		})
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakePrometheus) received(path string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.queries[path]...)
+}
+
+func checkDashboard(t *testing.T, queries ...string) Creator {
+	t.Helper()
+	return func(in CreatorInput) ([]dashboard.Builder, error) {
+		panels := []row.Option{}
+		for _, q := range queries {
+			panels = append(panels, row.WithTimeSeries(q, timeseries.WithPrometheusTarget(q)))
+		}
+		b, err := dashboard.New("check", dashboard.UID("check"),
+			dashboard.VariableAsCustom("job", custom.Values(custom.ValuesMap{"api": "api", "web": "web"}), custom.Default("api")),
+			dashboard.Row("queries", panels...))
+		return []dashboard.Builder{b}, err
+	}
+}
+
+func TestCheckQueries(t *testing.T) {
+	tests := []struct {
+		query string
+		state QueryState
+		error string
+	}{
+		{query: "sum(ok)", state: QueryOK},
+		{query: "sum(empty)", state: QueryEmpty},
+		{query: "sum(many)", state: QueryHighSeries},
+		{query: "sum(bad)", state: QueryError, error: "bad_data: parse error"},
+		{query: `sum(ok{job="$job"})`, state: QueryOK},
+		{query: `sum(ok{job="$unknown"})`, state: QueryError, error: "no default value for variables unknown"},
+	}
+	queries := []string{}
+	for _, tt := range tests {
+		queries = append(queries, tt.query)
+	}
+	prom := newFakePrometheus(t)
+	r := &Runner{Dashboard: checkDashboard(t, queries...)}
+	checks, err := r.CheckQueries(context.Background(), CheckQueriesOptions{Prometheus: prom.URL, MaxSeries: 3, Range: 30 * time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			kinds := []string{}
+			for _, ch := range checks {
+				if ch.Panel != tt.query {
+					continue
+				}
+				kinds = append(kinds, ch.Kind)
+				if ch.State != tt.state {
+					t.Errorf("%s query has state %s, want %s", ch.Kind, ch.State, tt.state)
+				}
+				if ch.Error != tt.error {
+					t.Errorf("%s query has error %q, want %q", ch.Kind, ch.Error, tt.error)
+				}
+			}
+			want := []string{"instant", "range"}
+			if tt.error != "" && strings.HasPrefix(tt.error, "no default value") {
+				want = []string{"instant"}
+			}
+			if !reflect.DeepEqual(kinds, want) {
+				t.Errorf("got checks %v, want %v", kinds, want)
+			}
+		})
+	}
+	for _, path := range []string{"/api/v1/query", "/api/v1/query_range"} {
+		got := prom.received(path)
+		if len(got) != 5 {
+			t.Errorf("%s received %d queries, want 5: %q", path, len(got), got)
+		}
+		for _, q := range got {
+			if strings.Contains(q, "$") {
+				t.Errorf("%s received unresolved query %s", path, q)
+			}
+		}
+		if !contains(got, `sum(ok{job="api"})`) {
+			t.Errorf("%s did not receive the resolved query, got %q", path, got)
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestQueryVariables(t *testing.T) {
+	builtin := map[string]string{
+		"__interval":      "1m",
+		"__interval_ms":   "60000",
+		"__rate_interval": "4m",
+		"__range":         "30m",
+		"__range_s":       "1800",
+		"__range_ms":      "1800000",
+	}
+	tests := []struct {
+		name  string
+		board func(t *testing.T) lintBoard
+		want  map[string]string
+	}{
+		{
+			name: "defaults",
+			board: func(t *testing.T) lintBoard {
+				b, err := dashboard.New("vars", dashboard.UID("vars"),
+					dashboard.VariableAsCustom("job", custom.Values(custom.ValuesMap{"api": "api", "web": "web"}), custom.Default("web")),
+					dashboard.VariableAsCustom("env", custom.Values(custom.ValuesMap{"prod": "prod"}), custom.IncludeAll(), custom.DefaultAll()),
+					dashboard.VariableAsInterval("step", interval.Values(interval.ValuesList{"1m", "5m"}), interval.Default("5m")),
+				)
+				if err != nil {
+					t.Fatal(err)
+				}
+				lb, err := parseLintBoard(b)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return lb
+			},
+			want: map[string]string{"__dashboard": "vars", "job": "web", "env": ".*", "step": "5m"},
+		},
+		{
+			name: "multiple values and auto interval",
+			board: func(t *testing.T) lintBoard {
+				return jsonLintBoard(t, `{"uid":"multi","templating":{"list":[
+					{"name":"job","type":"custom","current":{"value":["api","web"]}},
+					{"name":"env","type":"custom","current":{"value":["$__all"]}},
+					{"name":"step","type":"interval","current":{"value":"auto"}},
+					{"name":"empty","type":"custom","current":{"value":[]}}]}}`)
+			},
+			want: map[string]string{"__dashboard": "multi", "job": "api|web", "env": ".*", "step": "1m"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range builtin {
+				tt.want[name] = value
+			}
+			got := queryVariables(tt.board(t), 30*time.Minute, time.Minute)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			// grafana variables the check can not know are reported instead of guessed
+			_, unknown := resolveVariables(`x{a="$__all", b="$__org", c="$__user"}`, got)
+			if !reflect.DeepEqual(unknown, []string{"__all", "__org", "__user"}) {
+				t.Errorf("got unknown %q, want __all, __org and __user", unknown)
+			}
+		})
+	}
+}
+
+func TestResolveVariables(t *testing.T) {
+	values := map[string]string{"job": "api|web", "__rate_interval": "4m"}
+	tests := []struct {
+		query   string
+		want    string
+		unknown []string
+	}{
+		{query: `rate(x{job=~"$job"}[$__rate_interval])`, want: `rate(x{job=~"api|web"}[4m])`, unknown: []string{}},
+		{query: `rate(x{job=~"${job}"}[${__rate_interval}])`, want: `rate(x{job=~"api|web"}[4m])`, unknown: []string{}},
+		{query: `x{job=~"[[job]]"}`, want: `x{job=~"api|web"}`, unknown: []string{}},
+		{query: `x{pod="$pod", job="$job"}`, want: `x{pod="$pod", job="api|web"}`, unknown: []string{"pod"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, unknown := resolveVariables(tt.query, values)
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+			if !reflect.DeepEqual(unknown, tt.unknown) {
+				t.Errorf("got unknown %q, want %q", unknown, tt.unknown)
+			}
+		})
+	}
+}
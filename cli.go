@@ -5,12 +5,12 @@ import (
 	_ "embed"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
-	"os/signal"
 	"path"
 	"strings"
-	"syscall"
+	"time"
 
 	"github.com/K-Phoen/grabana"
 	"github.com/K-Phoen/grabana/dashboard"
@@ -20,12 +20,12 @@ import (
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/go-connections/nat"
+	"github.com/fasibio/grabana_cli_starter/recordingrules"
 	"github.com/google/uuid"
 	"github.com/testcontainers/testcontainers-go"
 	testContainerNetwork "github.com/testcontainers/testcontainers-go/network"
 	"github.com/testcontainers/testcontainers-go/wait"
 	"github.com/urfave/cli/v2"
-	"gopkg.in/yaml.v2"
 )
 
 type CliValues = string
@@ -38,6 +38,8 @@ const (
 	CliDevDatasourceName string    = "datasource_name"
 	CliDevSubnet         string    = "subnet"
 	CliDevGateway                  = "gateway"
+	CliVar               CliValues = "var"
+	CliVarFile           CliValues = "var-file"
 )
 
 //go:embed prometheus.yml.tmpl
@@ -52,72 +54,100 @@ func DashboardBuilder(d DashboardCreator) Option {
 		if runner.Dashboard != nil {
 			return fmt.Errorf("Dashboard already set")
 		}
-		runner.Dashboard = d
+		runner.Dashboard = cliCreator(d)
 		return nil
 	}
 }
 
 func DefaultDevRunDataSource(value string) Option {
-	return func(runner *Runner, app *cli.App) error {
-		for _, c := range app.Commands {
-			if c.Name == "dev" {
-				for _, sc := range c.Subcommands {
-					if sc.Name == "run" {
-						for _, f := range sc.Flags {
-							if helper.Includes(f.Names(), func(name string) bool { return name == CliDevDatasourceName }) {
-								strFlag, ok := f.(*cli.StringFlag)
-								if !ok {
-									return fmt.Errorf("Oh shit something big goes wrong")
-								}
-								strFlag.Value = value
-								strFlag.Required = false
-							}
-						}
-					}
-				}
-
-			}
-		}
-
-		return nil
-	}
+	return DefaultFlagValue("dev run", CliDevDatasourceName, value)
 }
 
 func DefaultDashboardCliFlagValue(key CliValues, value string) Option {
-	return func(runner *Runner, app *cli.App) error {
-		for _, c := range app.Commands {
-			if c.Name == "dashboard" {
-				for _, f := range c.Flags {
-					if helper.Includes(f.Names(), func(name string) bool { return name == key }) {
-						strFlag, ok := f.(*cli.StringFlag)
-						if !ok {
-							return fmt.Errorf("Oh shit something big goes wrong")
-						}
-						strFlag.Value = value
-					}
-				}
-			}
-		}
-
-		return nil
-	}
+	return DefaultFlagValue("dashboard", key, value)
 }
 
 type Runner struct {
-	Client    *grabana.Client
-	Ctx       context.Context
-	Dashboard DashboardCreator
+	Server            string
+	Client            *grabana.Client
+	HTTPClient        *http.Client
+	Logger            *slog.Logger
+	OrgID             int
+	Ctx               context.Context
+	RequestTimeout    time.Duration
+	Dashboard         Creator
+	Mutators          []DashboardMutator
+	Validators        []Validator
+	Compatibility     []CompatibilityRule
+	GrafanaVersion    string
+	RecordingMaps     []*recordingrules.RecodingMap
+	EnvironmentSchema []EnvironmentVariable
+	Environment       Environment
+	BuildInfo         BuildInfo
+	configFile        string
+	configValues      []configValue
+	cancels           []context.CancelFunc
 }
 
 func GetFlagEnvByFlagName(flagName, appName string) string {
-	return fmt.Sprintf("%s_%s", appName, strings.ToUpper(flagName))
+	return fmt.Sprintf("%s_%s", appName, strings.ToUpper(strings.ReplaceAll(flagName, "-", "_")))
+}
+
+// creatorFlags are the flags every command running the DashboardCreator needs
+func creatorFlags(appName string) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    CliFolderName,
+			EnvVars: []string{GetFlagEnvByFlagName(CliFolderName, appName)},
+			Usage:   "GrafanaFolder to create dashboards",
+		},
+		&cli.StringSliceFlag{
+			Name:    CliVar,
+			EnvVars: []string{GetFlagEnvByFlagName(CliVar, appName)},
+			Usage:   "environment value passed to the dashboards as key=value (repeatable)",
+		},
+		&cli.StringFlag{
+			Name:    CliVarFile,
+			EnvVars: []string{GetFlagEnvByFlagName(CliVarFile, appName)},
+			Usage:   "yaml file with environment values (key: value), overwritten by --var",
+		},
+	}
+}
+
+func appFlags(appName string) []cli.Flag {
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:    CliConfig,
+			EnvVars: []string{GetFlagEnvByFlagName(CliConfig, appName)},
+			Value:   DefaultConfigPath(appName),
+			Usage:   "yaml or toml file with flag values by command path (dashboard: {server: ...})",
+		},
+		outputFlag(appName),
+	}
+	return append(flags, logFlags(appName)...)
+}
+
+func dashboardFlags(appName string) []cli.Flag {
+	flags := append(creatorFlags(appName), filterFlags(appName)...)
+	flags = append(flags, timeoutFlags(appName)...)
+	flags = append(flags, &cli.StringFlag{
+		Name:    CliServer,
+		EnvVars: []string{GetFlagEnvByFlagName(CliServer, appName)},
+		Usage:   "grafana url",
+	})
+	flags = append(flags, orgFlags(appName)...)
+	flags = append(flags, compatibilityFlags(appName)...)
+	return append(flags, authFlags(appName)...)
 }
 
 func NewCli(appName string, options ...Option) (*cli.App, error) {
 	runner := Runner{}
 	app := &cli.App{
-		Usage: "vault-server",
-
+		Name:   appName,
+		Usage:  "Manage grafana dashboards as code",
+		Flags:  appFlags(appName),
+		Before: runner.BeforeApp,
+		After:  runner.AfterApp,
 		Commands: []*cli.Command{
 			{
 				Name:   "dashboard",
@@ -126,49 +156,104 @@ func NewCli(appName string, options ...Option) (*cli.App, error) {
 				Subcommands: []*cli.Command{
 					{
 						Name:   "apply",
-						Action: runner.Apply,
+						Action: runner.applyAction,
 						Usage:  "Upload Dashboard to target configuration",
 					},
 					{
 						Name:   "destroy",
-						Action: runner.Destroy,
+						Action: runner.destroyAction,
 						Usage:  "Remove Dashboard from target configuration",
+						Flags:  destroyFlags(appName),
 					},
 					{
 						Name:   "plan",
-						Action: runner.Plan,
+						Action: runner.planAction,
 						Usage:  "Upload Dashboard to target configuration",
 					},
-				},
-				Flags: []cli.Flag{
-					&cli.StringFlag{
-						Name:    CliFolderName,
-						EnvVars: []string{GetFlagEnvByFlagName(CliFolderName, appName)},
-						Usage:   "GrafanaFolder to create dashboards",
+					{
+						Name:   "status",
+						Action: runner.statusAction,
+						Usage:  "Compare every dashboard with grafana: missing, in-sync, out-of-sync or modified",
 					},
-					&cli.StringFlag{
-						Name:    CliServer,
-						EnvVars: []string{GetFlagEnvByFlagName(CliServer, appName)},
-						Usage:   "grafana url",
+					{
+						Name:   "list",
+						Action: runner.listAction,
+						Usage:  "List all dashboards of the creator and which are selected by the filters",
 					},
-					&cli.StringFlag{
-						Name:     CliApiKey,
-						EnvVars:  []string{GetFlagEnvByFlagName(CliApiKey, appName)},
-						Required: true,
-						Usage:    "grafana api key",
+				},
+				Flags: dashboardFlags(appName),
+			},
+			{
+				Name:   "version",
+				Usage:  "Print version, linked grabana/sdk versions and a hash of all dashboards",
+				Before: runner.withEnvironment,
+				Action: runner.PrintVersion,
+				Flags:  creatorFlags(appName),
+			},
+			{
+				Name:  "config",
+				Usage: "Inspect the configuration",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "print",
+						Usage:  "Show the effective flag values and their source",
+						Action: runner.PrintConfig,
 					},
 				},
 			},
 			{
 				Name:   "toYaml",
-				Action: runner.ToYaml,
-				Flags: []cli.Flag{
+				Usage:  "Write all dashboards as yaml",
+				Before: runner.withEnvironment,
+				Action: runner.toYamlAction,
+				Flags: append(append(creatorFlags(appName), filterFlags(appName)...),
 					&cli.StringFlag{
 						Name:    CliYamlTargetFile,
 						EnvVars: []string{GetFlagEnvByFlagName(CliYamlTargetFile, appName)},
 						Value:   "target.yml",
 						Usage:   "file to save yaml",
 					},
+				),
+			},
+			{
+				Name:   "lint",
+				Usage:  "Check the generated dashboards offline for structural problems",
+				Before: runner.withEnvironment,
+				Action: runner.lintAction,
+				Flags:  append(creatorFlags(appName), filterFlags(appName)...),
+			},
+			{
+				Name:   "inventory",
+				Usage:  "Export the metrics used by the dashboards and recording rules, optionally compared with a /metrics output",
+				Before: runner.withEnvironment,
+				Action: runner.inventoryAction,
+				Flags:  inventoryFlags(appName),
+			},
+			{
+				Name:   "docs",
+				Usage:  "Write markdown (and html) documentation of every dashboard",
+				Before: runner.withEnvironment,
+				Action: runner.docsAction,
+				Flags:  docsFlags(appName),
+			},
+			{
+				Name:   "preview",
+				Usage:  "Print the layout of every dashboard as ascii wireframe and optionally write it as svg",
+				Before: runner.withEnvironment,
+				Action: runner.previewAction,
+				Flags:  previewFlags(appName),
+			},
+			{
+				Name:  "check",
+				Usage: "Check the dashboards against live datasources",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "queries",
+						Usage:  "Run every prometheus query and report errors, empty results and high series counts",
+						Before: runner.beforeCheck,
+						Action: runner.checkQueriesAction,
+						Flags:  checkQueriesFlags(appName),
+					},
 				},
 			},
 			{
@@ -200,6 +285,11 @@ func NewCli(appName string, options ...Option) (*cli.App, error) {
 								EnvVars: []string{GetFlagEnvByFlagName(CliDevGateway, appName)},
 								Value:   "192.168.192.1",
 							},
+							&cli.StringFlag{
+								Name:    CliDevOrg,
+								EnvVars: []string{GetFlagEnvByFlagName(CliDevOrg, appName)},
+								Usage:   "create this grafana organisation and set up datasource and api key inside it",
+							},
 						},
 						After: runner.startDev,
 					},
@@ -217,84 +307,100 @@ func NewCli(appName string, options ...Option) (*cli.App, error) {
 }
 
 func (r *Runner) BeforeDev(c *cli.Context) error {
-	r.Ctx = context.Background()
-	r.Client = grabana.NewClient(&http.Client{}, c.String(CliServer), grabana.WithAPIToken(c.String(CliApiKey)))
+	r.Ctx = r.rootContext()
 
-	return nil
+	return r.newClient(c, false)
 }
 
-func (r *Runner) Before(c *cli.Context) error {
+// offlineDashboardCommands do not talk to grafana so they need no credentials
+var offlineDashboardCommands = []string{"plan", "list"}
 
-	r.Ctx = context.Background()
-	r.Client = grabana.NewClient(&http.Client{}, c.String(CliServer), grabana.WithAPIToken(c.String(CliApiKey)))
+func (r *Runner) Before(c *cli.Context) error {
+	if c.String(CliOutput) == OutputCSV {
+		return fmt.Errorf("Output format %s is only supported by inventory", OutputCSV)
+	}
+	r.withTimeout(c)
+	r.GrafanaVersion = c.String(CliGrafanaVersion)
+	requireAuth := !helper.Includes(offlineDashboardCommands, func(name string) bool { return name == c.Args().First() })
+	if err := r.newClient(c, requireAuth); err != nil {
+		return err
+	}
+	if requireAuth {
+		if err := r.selectOrg(c); err != nil {
+			return err
+		}
+	}
 
-	return nil
+	return r.withEnvironment(c)
 }
 
-func (r *Runner) Destroy(c *cli.Context) error {
-	board, err := r.Dashboard(c.String(CliFolderName), c)
-	if err != nil {
-		return err
+// CreatorInput builds the input of the Creator out of the cli flags
+func (r *Runner) CreatorInput(c *cli.Context) CreatorInput {
+	return CreatorInput{
+		FolderName:  c.String(CliFolderName),
+		Environment: GetEnvironment(c),
+		Flags:       c,
 	}
+}
 
-	err = errors.Join(nil)
-	for _, b := range board {
-		tmpErr := r.Client.DeleteDashboard(r.Ctx, b.Internal().UID)
-		if tmpErr != nil {
-			err = errors.Join(err, fmt.Errorf("Error by %s: %w", b.Internal().UID, tmpErr))
+func (r *Runner) destroyAction(c *cli.Context) error {
+	opts := DestroyOptions{CreatorInput: r.CreatorInput(c), Filter: FilterFromValues(c), DryRun: c.Bool(CliDryRun)}
+	if !opts.DryRun {
+		missing, err := r.confirmDestroy(c, opts)
+		if err != nil {
+			return err
 		}
+		opts.Missing = missing
 	}
+	results, err := r.Destroy(r.Ctx, opts)
+	if opts.DryRun && c.String(CliOutput) == OutputText {
+		return errors.Join(err, printDestroyPlan(os.Stdout, results))
+	}
+	if format := c.String(CliOutput); format != OutputText {
+		return errors.Join(err, writeReports(os.Stdout, format, results))
+	}
+	r.logSkipped(results)
 	return err
 }
 
-func (r *Runner) Apply(c *cli.Context) error {
-	folder, err := r.Client.FindOrCreateFolder(r.Ctx, c.String(CliFolderName))
-	if err != nil {
-		return fmt.Errorf("Could not find or create folder: %w\n", err)
+func (r *Runner) applyAction(c *cli.Context) error {
+	results, err := r.Apply(r.Ctx, ApplyOptions{CreatorInput: r.CreatorInput(c), Filter: FilterFromValues(c)})
+	if format := c.String(CliOutput); format != OutputText {
+		return errors.Join(err, writeReports(os.Stdout, format, results))
 	}
-	board, err := r.Dashboard(c.String(CliFolderName), c)
-	if err != nil {
-		return err
-	}
-	err = errors.Join(nil)
-	for _, b := range board {
-		dash, tmpErr := r.Client.UpsertDashboard(r.Ctx, folder, b)
-		if tmpErr != nil {
-			err = errors.Join(err, fmt.Errorf("Could not create dashboard: %w\n", tmpErr))
-		} else {
-			fmt.Printf("The deed is done:\n%s\n", c.String(CliServer)+dash.URL)
+	printViolations(results)
+	for _, res := range results {
+		if res.Status == StatusDone {
+			fmt.Printf("The deed is done:\n%s\n", res.URL)
 		}
 	}
+	r.logSkipped(results)
 	return err
 }
-func (r *Runner) ToYaml(c *cli.Context) error {
+
+func (r *Runner) toYamlAction(c *cli.Context) error {
 
 	filepath := c.String(CliYamlTargetFile)
 	f, err := os.Create(filepath)
 	if err != nil {
 		return err
 	}
-	encoder := yaml.NewEncoder(f)
+	defer f.Close()
 
-	return encoder.Encode(&r.Dashboard)
+	return r.WriteYaml(f, ExportOptions{CreatorInput: r.CreatorInput(c), Filter: FilterFromValues(c)})
 }
 
-func (r *Runner) Plan(c *cli.Context) error {
-	board, err := r.Dashboard(c.String(CliFolderName), c)
-	if err != nil {
-		return err
+func (r *Runner) planAction(c *cli.Context) error {
+	results, err := r.Plan(r.Ctx, PlanOptions{CreatorInput: r.CreatorInput(c), Filter: FilterFromValues(c)})
+	if format := c.String(CliOutput); format != OutputText {
+		return errors.Join(err, writeReports(os.Stdout, format, results))
 	}
-	err = errors.Join(nil)
-
-	for _, b := range board {
-		json, tmpErr := b.MarshalIndentJSON()
-		if tmpErr != nil {
-			err = errors.Join(err, fmt.Errorf("Error by %s: %w", b.Internal().UID, tmpErr))
-		}
-		fmt.Println(string(json))
+	printEnvironment(r.Environment)
+	for _, res := range results {
+		fmt.Println(string(res.Model))
 	}
-
-	return nil
+	printViolations(results)
+	return err
 }
 
 // EnsureDir checks if given directory exist, creates if not
@@ -336,8 +442,6 @@ func (r *Runner) startDev(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, syscall.SIGINT, syscall.SIGTERM)
 	ctx := context.Background()
 	pwd, err := os.Getwd()
 	if err != nil {
@@ -425,7 +529,15 @@ func (r *Runner) startDev(c *cli.Context) error {
 	if err != nil {
 		return fmt.Errorf("error get prometheus endpoint: %w", err)
 	}
-	client := grabana.NewClient(&http.Client{}, grafanaUrl, grabana.WithBasicAuth("admin", "admin"))
+	devHTTPClient := &http.Client{Transport: &authTransport{base: http.DefaultTransport, user: "admin", password: "admin"}}
+	client := grabana.NewClient(devHTTPClient, grafanaUrl)
+	orgID := 1
+	if orgName := c.String(CliDevOrg); orgName != "" {
+		orgID, err = createDevOrg(r.Ctx, devHTTPClient, grafanaUrl, orgName)
+		if err != nil {
+			return err
+		}
+	}
 	prometheusDatasource, err := prometheus.New(c.String(CliDevDatasourceName), fmt.Sprintf("http://%s:9090", prometheusContainerName))
 	if err != nil {
 		return err
@@ -448,9 +560,10 @@ func (r *Runner) startDev(c *cli.Context) error {
 	fmt.Printf("Grafana endpoint: %s \n", grafanaUrl)
 	fmt.Printf("\tGrafana user: admin \n")
 	fmt.Printf("\tGrafana password: admin \n")
+	fmt.Printf("\tGrafana org id: %d \n", orgID)
 	fmt.Printf("\tPrometheus Datasourcename: %s\n", c.String(CliDevDatasourceName))
 	fmt.Printf("\tApi key: %s \n", apiKey)
 	fmt.Printf("Simple run\n go run . dashboard --server %s --apikey %s apply\n", grafanaUrl, apiKey)
-	<-done
+	<-r.Ctx.Done()
 	return nil
 }
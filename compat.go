@@ -0,0 +1,185 @@
+package grabanaclistarter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/K-Phoen/grabana/dashboard"
+	"github.com/K-Phoen/sdk"
+	"github.com/urfave/cli/v2"
+)
+
+const CliGrafanaVersion CliValues = "grafana-version"
+
+func compatibilityFlags(appName string) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    CliGrafanaVersion,
+			EnvVars: []string{GetFlagEnvByFlagName(CliGrafanaVersion, appName)},
+			Usage:   "grafana version to check the dashboards against (default: the version of --server, plan only checks with this flag)",
+		},
+	}
+}
+
+// CompatibilityRule declares from which grafana version on a panel type is available, deprecated or removed.
+// Versions are "major.minor", empty means never
+type CompatibilityRule struct {
+	PanelType string
+	// Match is used instead of PanelType to declare rules for panel options
+	Match func(p *sdk.Panel) bool
+	// Feature names the rule in messages, default is the PanelType
+	Feature string
+	// Since is the first version supporting the feature
+	Since string
+	// DeprecatedIn reports a SeverityWarning from this version on
+	DeprecatedIn string
+	// RemovedIn reports a SeverityError from this version on
+	RemovedIn   string
+	Replacement string
+}
+
+func (c CompatibilityRule) matches(p *sdk.Panel) bool {
+	if c.Match != nil {
+		return c.Match(p)
+	}
+	return p.Type == c.PanelType
+}
+
+func (c CompatibilityRule) feature() string {
+	if c.Feature != "" {
+		return c.Feature
+	}
+	return "panel type " + c.PanelType
+}
+
+// DefaultCompatibility is used if no rules are set by WithCompatibility. Grafana 11 disables angular, which
+// removes the old graph, singlestat and table panels
+var DefaultCompatibility = []CompatibilityRule{
+	{PanelType: "graph", DeprecatedIn: "8.0", RemovedIn: "11.0", Replacement: "timeseries"},
+	{PanelType: "singlestat", DeprecatedIn: "7.0", RemovedIn: "11.0", Replacement: "stat"},
+	{PanelType: "table-old", DeprecatedIn: "7.0", RemovedIn: "11.0", Replacement: "table"},
+	{PanelType: "grafana-piechart-panel", DeprecatedIn: "8.0", RemovedIn: "11.0", Replacement: "piechart"},
+	{PanelType: "grafana-worldmap-panel", DeprecatedIn: "9.0", RemovedIn: "11.0", Replacement: "geomap"},
+	{PanelType: "timeseries", Since: "7.4"},
+	{PanelType: "barchart", Since: "8.0"},
+	{PanelType: "state-timeline", Since: "8.0"},
+	{PanelType: "status-history", Since: "8.0"},
+	{PanelType: "histogram", Since: "8.0"},
+	{PanelType: "geomap", Since: "8.1"},
+}
+
+// WithCompatibility replaces DefaultCompatibility by the version matrix of the project. A later call replaces
+// the rules of an earlier one, append to DefaultCompatibility to keep its rules
+func WithCompatibility(rules ...CompatibilityRule) Option {
+	return func(runner *Runner, app *cli.App) error {
+		runner.Compatibility = rules
+		return nil
+	}
+}
+
+// grafanaVersion is major and minor of a version like 10.4.1, 11.0.0-pre or v9.5.2+security-01
+type grafanaVersion [2]int
+
+func parseGrafanaVersion(version string) (grafanaVersion, error) {
+	parts := strings.SplitN(strings.TrimPrefix(strings.TrimSpace(version), "v"), ".", 3)
+	res := grafanaVersion{}
+	for i := 0; i < len(parts) && i < 2; i++ {
+		n, err := strconv.Atoi(strings.TrimRightFunc(parts[i], func(r rune) bool { return r < '0' || r > '9' }))
+		if err != nil {
+			return res, fmt.Errorf("Invalid grafana version %s", version)
+		}
+		res[i] = n
+	}
+	return res, nil
+}
+
+func (v grafanaVersion) less(o grafanaVersion) bool {
+	return v[0] < o[0] || (v[0] == o[0] && v[1] < o[1])
+}
+
+// reached reports if v is at least the rule version, an empty rule version is never reached
+func (v grafanaVersion) reached(ruleVersion string) bool {
+	if ruleVersion == "" {
+		return false
+	}
+	o, err := parseGrafanaVersion(ruleVersion)
+	return err == nil && !v.less(o)
+}
+
+// CompatibilityValidator checks the panels against the rules for the grafana version
+func CompatibilityValidator(version string, rules []CompatibilityRule) (Validator, error) {
+	v, err := parseGrafanaVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	return func(b dashboard.Builder) []Violation {
+		res := []Violation{}
+		for _, ref := range boardPanels(b.Internal()) {
+			for _, rule := range rules {
+				if !rule.matches(ref.Panel) {
+					continue
+				}
+				replacement := ""
+				if rule.Replacement != "" {
+					replacement = ", use " + rule.Replacement
+				}
+				switch {
+				case rule.Since != "" && !v.reached(rule.Since):
+					res = append(res, panelViolation(b, ref.Panel, "grafana-compatibility", SeverityError, fmt.Sprintf("%s needs grafana %s, server is %s", rule.feature(), rule.Since, version)))
+				case v.reached(rule.RemovedIn):
+					res = append(res, panelViolation(b, ref.Panel, "grafana-compatibility", SeverityError, fmt.Sprintf("%s is not supported by grafana %s%s", rule.feature(), version, replacement)))
+				case v.reached(rule.DeprecatedIn):
+					res = append(res, panelViolation(b, ref.Panel, "grafana-compatibility", SeverityWarning, fmt.Sprintf("%s is deprecated since grafana %s%s", rule.feature(), rule.DeprecatedIn, replacement)))
+				}
+			}
+		}
+		return res
+	}, nil
+}
+
+// FetchGrafanaVersion reads the version of the server from /api/health
+func (r *Runner) FetchGrafanaVersion(ctx context.Context) (string, error) {
+	health := struct {
+		Version string `json:"version"`
+	}{}
+	if err := r.grafanaRequest(ctx, http.MethodGet, "/api/health", nil, &health); err != nil {
+		return "", fmt.Errorf("Could not read grafana version: %w", err)
+	}
+	return health.Version, nil
+}
+
+// detectGrafanaVersion sets Runner.GrafanaVersion from the server if it is not set yet. The compatibility
+// check only runs with a known GrafanaVersion
+func (r *Runner) detectGrafanaVersion(ctx context.Context) {
+	if r.GrafanaVersion != "" || r.HTTPClient == nil || r.Server == "" {
+		return
+	}
+	version, err := r.FetchGrafanaVersion(ctx)
+	if err != nil {
+		r.logger().Warn("Skipping grafana compatibility check", "error", err)
+		return
+	}
+	r.logger().Debug("Grafana version", "version", version)
+	r.GrafanaVersion = version
+}
+
+// compatibilityValidator checks the dashboards against the version matrix, it is nil if the grafana version is unknown
+func (r *Runner) compatibilityValidator() Validator {
+	if r.GrafanaVersion == "" {
+		return nil
+	}
+	rules := r.Compatibility
+	if rules == nil {
+		rules = DefaultCompatibility
+	}
+	validator, err := CompatibilityValidator(r.GrafanaVersion, rules)
+	if err != nil {
+		return func(b dashboard.Builder) []Violation {
+			return []Violation{{Dashboard: b.Internal().UID, Rule: "grafana-compatibility", Severity: SeverityWarning, Message: err.Error()}}
+		}
+	}
+	return validator
+}
@@ -0,0 +1,82 @@
+package grabanaclistarter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/K-Phoen/grabana/row"
+	"github.com/K-Phoen/grabana/timeseries"
+)
+
+func TestParseGrafanaVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    grafanaVersion
+		wantErr bool
+	}{
+		{version: "10.4.1", want: grafanaVersion{10, 4}},
+		{version: "v9.5.2+security-01", want: grafanaVersion{9, 5}},
+		{version: "11.0.0-pre", want: grafanaVersion{11, 0}},
+		{version: "11", want: grafanaVersion{11, 0}},
+		{version: "main", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseGrafanaVersion(tt.version)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseGrafanaVersion(%s) error %v, wantErr %t", tt.version, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parseGrafanaVersion(%s) = %v, want %v", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestCompatibilityValidator(t *testing.T) {
+	rules := []CompatibilityRule{
+		{PanelType: "timeseries", Since: "7.4", DeprecatedIn: "10.0", RemovedIn: "11.0", Replacement: "xychart"},
+	}
+	tests := []struct {
+		version string
+		want    []string
+	}{
+		{version: "7.3.9", want: []string{"[error] policy / cpu: panel type timeseries needs grafana 7.4, server is 7.3.9 (grafana-compatibility)"}},
+		{version: "7.4.0"},
+		{version: "9.5.2"},
+		{version: "10.0.0", want: []string{"[warning] policy / cpu: panel type timeseries is deprecated since grafana 10.0, use xychart (grafana-compatibility)"}},
+		{version: "10.4.1", want: []string{"[warning] policy / cpu: panel type timeseries is deprecated since grafana 10.0, use xychart (grafana-compatibility)"}},
+		{version: "11.0.0-pre", want: []string{"[error] policy / cpu: panel type timeseries is not supported by grafana 11.0.0-pre, use xychart (grafana-compatibility)"}},
+	}
+	board := policyBoard(t, panelRow(row.WithTimeSeries("cpu", timeseries.Span(6))))
+	for _, tt := range tests {
+		validator, err := CompatibilityValidator(tt.version, rules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, v := range validator(board) {
+			got = append(got, v.String())
+		}
+		if tt.want == nil {
+			tt.want = []string{}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.version, got, tt.want)
+		}
+	}
+	if _, err := CompatibilityValidator("main", rules); err == nil {
+		t.Error("expected an error for version main")
+	}
+}
+
+func TestWithCompatibility(t *testing.T) {
+	r := &Runner{}
+	for _, rule := range []CompatibilityRule{{PanelType: "graph", RemovedIn: "11.0"}, {PanelType: "table-old", RemovedIn: "11.0"}} {
+		if err := WithCompatibility(rule)(r, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(r.Compatibility) != 1 || r.Compatibility[0].PanelType != "table-old" {
+		t.Errorf("got rules %+v, want only the ones of the last call", r.Compatibility)
+	}
+}
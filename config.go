@@ -0,0 +1,242 @@
+package grabanaclistarter
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/BurntSushi/toml"
+	"github.com/cryptvault-cloud/helper"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+const CliConfig CliValues = "config"
+
+// configValue is a flag default taken from the config file
+type configValue struct {
+	path  string
+	flag  string
+	value any
+}
+
+// DefaultConfigPath is the config file looked up if --config is not set: <UserConfigDir>/<appName>/config.yaml
+func DefaultConfigPath(appName string) string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, appName, "config.yaml")
+}
+
+// readConfigFile parses a yaml or toml (by extension) file into a nested map by command path
+func readConfigFile(file string) (map[string]any, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]any{}
+	switch strings.ToLower(path.Ext(file)) {
+	case ".toml":
+		err = toml.Unmarshal(content, &values)
+	default:
+		raw := map[any]any{}
+		err = yaml.Unmarshal(content, &raw)
+		if err == nil {
+			values = normalizeYaml(raw).(map[string]any)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing config file %s: %w", file, err)
+	}
+	return values, nil
+}
+
+// normalizeYaml turns the map[any]any of yaml.v2 into map[string]any
+func normalizeYaml(v any) any {
+	switch value := v.(type) {
+	case map[any]any:
+		res := make(map[string]any, len(value))
+		for k, e := range value {
+			res[fmt.Sprint(k)] = normalizeYaml(e)
+		}
+		return res
+	case []any:
+		for i, e := range value {
+			value[i] = normalizeYaml(e)
+		}
+		return value
+	}
+	return v
+}
+
+// collectConfigValues matches the config map against the command tree
+func collectConfigValues(cmdPath string, flags []cli.Flag, subcommands []*cli.Command, values map[string]any) ([]configValue, error) {
+	res := []configValue{}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := values[k]
+		if sub, ok := v.(map[string]any); ok {
+			var cmd *cli.Command
+			for _, c := range subcommands {
+				if c.HasName(k) {
+					cmd = c
+				}
+			}
+			if cmd == nil {
+				return nil, fmt.Errorf("Unknown command %q in config file", strings.TrimSpace(cmdPath+" "+k))
+			}
+			subValues, err := collectConfigValues(strings.TrimSpace(cmdPath+" "+cmd.Name), cmd.Flags, cmd.Subcommands, sub)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, subValues...)
+			continue
+		}
+		if findFlag(flags, k) == nil {
+			return nil, fmt.Errorf("Unknown flag %q for command %q in config file", k, cmdPath)
+		}
+		res = append(res, configValue{path: cmdPath, flag: k, value: v})
+	}
+	return res, nil
+}
+
+// LoadConfig applies the config file as flag defaults, so precedence is flag > env > file > Option default
+func (r *Runner) LoadConfig(c *cli.Context) error {
+	file := c.String(CliConfig)
+	if file == "" {
+		return nil
+	}
+	values, err := readConfigFile(file)
+	if err != nil {
+		if os.IsNotExist(err) && !c.IsSet(CliConfig) {
+			return nil
+		}
+		return err
+	}
+	configValues, err := collectConfigValues("", c.App.Flags, c.App.Commands, values)
+	if err != nil {
+		return err
+	}
+	applied := []configValue{}
+	for _, v := range configValues {
+		if v.path == "" {
+			ok, err := applyAppConfigValue(c, v)
+			if err != nil {
+				return fmt.Errorf("Error in config file %s: %w", file, err)
+			}
+			if ok {
+				applied = append(applied, v)
+			}
+			continue
+		}
+		cmd, err := findCommand(c.App, v.path)
+		if err != nil {
+			return err
+		}
+		if err := setFlagDefault(findFlag(cmd.Flags, v.flag), v.value); err != nil {
+			return fmt.Errorf("Error in config file %s: %w", file, err)
+		}
+		applied = append(applied, v)
+	}
+	r.configFile = file
+	r.configValues = applied
+	return nil
+}
+
+// applyAppConfigValue sets an app flag from the config file. App flags are already parsed when the file is
+// read, so the value is set directly unless the flag or its env var set it. Returns if the value is used
+func applyAppConfigValue(c *cli.Context, v configValue) (bool, error) {
+	f := findFlag(c.App.Flags, v.flag)
+	name := f.Names()[0]
+	if name == CliConfig {
+		return false, fmt.Errorf("--%s can not be set in the config file", CliConfig)
+	}
+	if c.IsSet(name) {
+		return false, nil
+	}
+	if err := setFlagDefault(f, v.value); err != nil {
+		return false, err
+	}
+	return true, c.Set(name, fmt.Sprint(v.value))
+}
+
+func isSecretFlag(name string) bool {
+	return name == CliApiKey || name == CliBasicAuthPassword
+}
+
+var headerValue = regexp.MustCompile(`=[^,"]*`)
+
+// maskConfigValue hides secrets and the values of --header, which often carry credentials
+func maskConfigValue(name, value string) string {
+	switch {
+	case value == "":
+		return value
+	case isSecretFlag(name):
+		return "****"
+	case name == CliHeader:
+		return headerValue.ReplaceAllString(value, "=****")
+	}
+	return value
+}
+
+// PrintConfig shows the effective value of every flag and where it comes from
+func (r *Runner) PrintConfig(c *cli.Context) error {
+	if r.configFile != "" {
+		fmt.Printf("Config file: %s\n", r.configFile)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "COMMAND\tFLAG\tVALUE\tSOURCE")
+	var walk func(cmdPath string, cmds []*cli.Command)
+	walk = func(cmdPath string, cmds []*cli.Command) {
+		for _, cmd := range cmds {
+			p := strings.TrimSpace(cmdPath + " " + cmd.Name)
+			for _, f := range cmd.Flags {
+				r.printConfigFlag(w, c, p, f)
+			}
+			walk(p, cmd.Subcommands)
+		}
+	}
+	for _, f := range c.App.Flags {
+		r.printConfigFlag(w, c, "", f)
+	}
+	walk("", c.App.Commands)
+	return w.Flush()
+}
+
+func (r *Runner) printConfigFlag(w *tabwriter.Writer, c *cli.Context, cmdPath string, f cli.Flag) {
+	docFlag, ok := f.(cli.DocGenerationFlag)
+	if !ok {
+		return
+	}
+	name := f.Names()[0]
+	if name == cli.HelpFlag.Names()[0] {
+		return
+	}
+	value, source := docFlag.GetValue(), "default"
+	for _, v := range r.configValues {
+		if v.path == cmdPath && helper.Includes(f.Names(), func(n string) bool { return n == v.flag }) {
+			source = "file"
+		}
+	}
+	for _, env := range docFlag.GetEnvVars() {
+		if envValue, ok := os.LookupEnv(env); ok {
+			value, source = envValue, "env "+env
+			break
+		}
+	}
+	// app flags are parsed before "config print" runs, so a value given on the command line is known
+	if cmdPath == "" && source == "default" && c.IsSet(name) {
+		value, source = fmt.Sprint(c.Value(name)), "flag"
+	}
+	fmt.Fprintf(w, "%s\t--%s\t%s\t%s\n", cmdPath, name, maskConfigValue(name, value), source)
+}
@@ -0,0 +1,115 @@
+package grabanaclistarter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+// probeCli returns an app with a probe command reporting the effective values of its flag and the app flags
+func probeCli(t *testing.T, got map[string]string) *cli.App {
+	t.Helper()
+	app, err := NewCli("cfgtest", func(runner *Runner, app *cli.App) error {
+		app.Commands = append(app.Commands, &cli.Command{
+			Name: "probe",
+			Flags: []cli.Flag{&cli.StringFlag{
+				Name:    "name",
+				EnvVars: []string{GetFlagEnvByFlagName("name", app.Name)},
+				Value:   "default",
+			}},
+			Action: func(c *cli.Context) error {
+				got["name"] = c.String("name")
+				got[CliOutput] = c.String(CliOutput)
+				got[CliLogLevel] = c.String(CliLogLevel)
+				return nil
+			},
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		env    map[string]string
+		args   []string
+		want   map[string]string
+	}{
+		{
+			name:   "default",
+			config: "{}",
+			want:   map[string]string{"name": "default", CliOutput: OutputText, CliLogLevel: "info"},
+		},
+		{
+			name:   "file",
+			config: "output: json\nlog-level: warn\nprobe:\n  name: file\n",
+			want:   map[string]string{"name": "file", CliOutput: OutputJSON, CliLogLevel: "warn"},
+		},
+		{
+			name:   "env over file",
+			config: "output: json\nlog-level: warn\nprobe:\n  name: file\n",
+			env:    map[string]string{"cfgtest_NAME": "env", "cfgtest_OUTPUT": OutputYAML, "cfgtest_LOG_LEVEL": "error"},
+			want:   map[string]string{"name": "env", CliOutput: OutputYAML, CliLogLevel: "error"},
+		},
+		{
+			name:   "flag over env",
+			config: "output: json\nlog-level: warn\nprobe:\n  name: file\n",
+			env:    map[string]string{"cfgtest_NAME": "env", "cfgtest_OUTPUT": OutputYAML},
+			args:   []string{"--output", OutputText, "--log-level", "debug", "probe", "--name", "flag"},
+			want:   map[string]string{"name": "flag", CliOutput: OutputText, CliLogLevel: "debug"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			args := tt.args
+			if args == nil {
+				args = []string{"probe"}
+			}
+			got := map[string]string{}
+			err := probeCli(t, got).Run(append([]string{"cfgtest", "--config", writeConfig(t, tt.config)}, args...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, want := range tt.want {
+				if got[k] != want {
+					t.Errorf("%s is %q, want %q", k, got[k], want)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	tests := map[string]string{
+		"unknown flag":    "probe:\n  unknown: x\n",
+		"unknown command": "nope:\n  name: x\n",
+		"config in file":  "config: other.yaml\n",
+	}
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := probeCli(t, map[string]string{}).Run([]string{"cfgtest", "--config", writeConfig(t, config), "probe"})
+			if err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package grabanaclistarter
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	CliTimeout        CliValues = "timeout"
+	CliRequestTimeout CliValues = "request-timeout"
+)
+
+func timeoutFlags(appName string) []cli.Flag {
+	return []cli.Flag{
+		&cli.DurationFlag{
+			Name:    CliTimeout,
+			EnvVars: []string{GetFlagEnvByFlagName(CliTimeout, appName)},
+			Usage:   "overall timeout of the command (0 = none)",
+		},
+		&cli.DurationFlag{
+			Name:    CliRequestTimeout,
+			EnvVars: []string{GetFlagEnvByFlagName(CliRequestTimeout, appName)},
+			Value:   30 * time.Second,
+			Usage:   "timeout of every single grafana request (0 = none)",
+		},
+	}
+}
+
+// BeforeApp creates the root context cancelled on SIGINT/SIGTERM and loads the config file
+func (r *Runner) BeforeApp(c *cli.Context) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	r.Ctx = ctx
+	r.cancels = append(r.cancels, stop)
+	go func() {
+		<-ctx.Done()
+		// restore the default signal handling, so a second Ctrl-C kills the process
+		stop()
+	}()
+	if err := r.LoadConfig(c); err != nil {
+		return err
+	}
+	return r.initLogger(c)
+}
+
+// AfterApp releases the root context
+func (r *Runner) AfterApp(c *cli.Context) error {
+	for _, cancel := range r.cancels {
+		cancel()
+	}
+	r.cancels = nil
+	return nil
+}
+
+func (r *Runner) rootContext() context.Context {
+	if r.Ctx == nil {
+		return context.Background()
+	}
+	return r.Ctx
+}
+
+// withTimeout derives r.Ctx from the root context limited by --timeout and sets RequestTimeout
+func (r *Runner) withTimeout(c *cli.Context) {
+	r.Ctx = r.rootContext()
+	r.RequestTimeout = c.Duration(CliRequestTimeout)
+	if timeout := c.Duration(CliTimeout); timeout > 0 {
+		ctx, cancel := context.WithTimeout(r.Ctx, timeout)
+		r.Ctx = ctx
+		r.cancels = append(r.cancels, cancel)
+	}
+}
+
+// requestContext detaches a single grafana request from ctx, so a cancelled ctx (Ctrl-C, --timeout) stops
+// between dashboards instead of aborting the request in flight. The request is bounded by RequestTimeout
+func (r *Runner) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx = context.WithoutCancel(ctx)
+	if r.RequestTimeout > 0 {
+		return context.WithTimeout(ctx, r.RequestTimeout)
+	}
+	return ctx, func() {}
+}
+
+func newHTTPClient(c *cli.Context) *http.Client {
+	return &http.Client{Timeout: c.Duration(CliRequestTimeout)}
+}
+
+// logSkipped lists the dashboards not processed because the context was done
+func (r *Runner) logSkipped(results []DashboardResult) {
+	for _, res := range results {
+		if res.Status == StatusSkipped {
+			r.logger().Warn("Not processed", "uid", res.UID, "title", res.Title, "action", res.Action)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package grabanaclistarter
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cryptvault-cloud/helper"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	CliYes    CliValues = "yes"
+	CliDryRun CliValues = "dry-run"
+)
+
+func destroyFlags(appName string) []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:    CliYes,
+			Aliases: []string{"y"},
+			EnvVars: []string{GetFlagEnvByFlagName(CliYes, appName)},
+			Usage:   "do not ask for confirmation (needed without tty)",
+		},
+		&cli.BoolFlag{
+			Name:    CliDryRun,
+			EnvVars: []string{GetFlagEnvByFlagName(CliDryRun, appName)},
+			Usage:   "only show what would be removed",
+		},
+	}
+}
+
+// DestroyPlan looks up every dashboard Destroy would remove without deleting anything.
+// Found dashboards are StatusPlanned with their live folder, not existing ones StatusMissing
+func (r *Runner) DestroyPlan(ctx context.Context, opts DestroyOptions) ([]DashboardResult, error) {
+	board, err := r.Dashboards(opts.CreatorInput, opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]DashboardResult, 0, len(board))
+	err = errors.Join(nil)
+	for _, b := range board {
+		if ctx.Err() != nil {
+			return results, errors.Join(err, ctx.Err())
+		}
+		res := newResult(b, opts.FolderName, "destroy")
+		res.Status = StatusPlanned
+		live, tmpErr := r.LiveDashboard(ctx, res.UID)
+		switch {
+		case isNotFound(tmpErr):
+			res.Status = StatusMissing
+		case tmpErr != nil:
+			res.Status = StatusFailed
+			res.Err = fmt.Errorf("Error by %s: %w", res.UID, tmpErr)
+			err = errors.Join(err, res.Err)
+		default:
+			res.Folder = live.Meta.FolderTitle
+			res.URL = r.Server + live.Meta.URL
+			res.Version = live.Version()
+		}
+		results = append(results, res)
+	}
+	return results, err
+}
+
+func printDestroyPlan(w io.Writer, results []DashboardResult) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "UID\tTITLE\tFOLDER\tSTATUS")
+	for _, res := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", res.UID, res.Title, res.Folder, res.Status)
+	}
+	return tw.Flush()
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// confirm asks to type one of expected
+func confirm(in io.Reader, out io.Writer, expected []string) (bool, error) {
+	fmt.Fprintf(out, "Type %q to confirm: ", strings.Join(expected, "\" or \""))
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	answer = strings.TrimSpace(answer)
+	return helper.Includes(expected, func(e string) bool { return e == answer }), nil
+}
+
+// confirmDestroy lists the existing dashboards and asks for confirmation unless --yes is set. It returns the
+// uids of the missing dashboards. Dashboards which could not be looked up are listed and removed as well
+func (r *Runner) confirmDestroy(c *cli.Context, opts DestroyOptions) ([]string, error) {
+	planned, err := r.DestroyPlan(r.Ctx, opts)
+	if planned == nil && err != nil {
+		return nil, err
+	}
+	missing := []string{}
+	remove := make([]DashboardResult, 0, len(planned))
+	for _, res := range planned {
+		switch res.Status {
+		case StatusMissing:
+			missing = append(missing, res.UID)
+		case StatusFailed:
+			r.logger().Warn("Could not look up dashboard, trying to remove it anyway", "uid", res.UID, "error", res.Err)
+			remove = append(remove, res)
+		default:
+			remove = append(remove, res)
+		}
+	}
+	out := os.Stdout
+	if c.String(CliOutput) != OutputText {
+		out = os.Stderr
+	}
+	if len(remove) == 0 {
+		fmt.Fprintln(out, "No dashboard to remove")
+		return missing, nil
+	}
+	fmt.Fprintln(out, "The following dashboards will be removed:")
+	if err := printDestroyPlan(out, remove); err != nil {
+		return nil, err
+	}
+	if c.Bool(CliYes) {
+		return missing, nil
+	}
+	if !isTerminal(os.Stdin) {
+		return nil, fmt.Errorf("Refusing to destroy without --%s on a non interactive terminal", CliYes)
+	}
+	expected := []string{c.App.Name}
+	if opts.FolderName != "" {
+		expected = append(expected, opts.FolderName)
+	}
+	ok, err := confirm(os.Stdin, out, expected)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("Destroy aborted")
+	}
+	return missing, nil
+}
@@ -0,0 +1,288 @@
+package grabanaclistarter
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	htmlTemplate "html/template"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/K-Phoen/grabana/dashboard"
+	"github.com/K-Phoen/sdk"
+	"github.com/fasibio/grabana_cli_starter/recordingrules"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	CliDocsDir  CliValues = "dir"
+	CliDocsHTML CliValues = "html"
+)
+
+//go:embed docs.md.tmpl
+var docsMarkdownTmpl string
+
+//go:embed docs.html.tmpl
+var docsHTMLTmpl string
+
+func docsFlags(appName string) []cli.Flag {
+	return append(append(creatorFlags(appName), filterFlags(appName)...),
+		&cli.StringFlag{
+			Name:    CliDocsDir,
+			EnvVars: []string{GetFlagEnvByFlagName(CliDocsDir, appName)},
+			Value:   "docs",
+			Usage:   "directory to write one file per dashboard to",
+		},
+		&cli.BoolFlag{
+			Name:    CliDocsHTML,
+			EnvVars: []string{GetFlagEnvByFlagName(CliDocsHTML, appName)},
+			Usage:   "write html next to the markdown",
+		},
+	)
+}
+
+type DocLink struct {
+	Title string
+	URL   string
+	Tags  []string
+}
+
+type DocQuery struct {
+	RefID      string
+	Datasource string
+	Expr       string
+	Legend     string
+	// Recorded are the rules of the registered RecodingMaps whose records are used by Expr
+	Recorded []recordingrules.RecordingRule
+}
+
+type DocPanel struct {
+	Title       string
+	Type        string
+	Description string
+	Queries     []DocQuery
+	Links       []DocLink
+}
+
+type DocRow struct {
+	Title  string
+	Panels []DocPanel
+}
+
+type DocVariable struct {
+	Name    string
+	Label   string
+	Type    string
+	Query   string
+	Default string
+}
+
+// DashboardDoc is the documentation of one dashboard
+type DashboardDoc struct {
+	UID       string
+	Title     string
+	Folder    string
+	Tags      []string
+	Links     []DocLink
+	Variables []DocVariable
+	Rows      []DocRow
+}
+
+type DocsOptions struct {
+	CreatorInput
+	Filter Filter
+}
+
+func docLinks(links []sdk.Link) []DocLink {
+	res := []DocLink{}
+	for _, l := range links {
+		link := DocLink{Title: l.Title, Tags: l.Tags}
+		if l.URL != nil {
+			link.URL = *l.URL
+		}
+		res = append(res, link)
+	}
+	return res
+}
+
+// docValue formats variable queries and values, which are strings, lists or objects depending on the type
+func docValue(v any) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case []any:
+		values := []string{}
+		for _, item := range value {
+			values = append(values, docValue(item))
+		}
+		return strings.Join(values, ", ")
+	case map[string]any:
+		if q, ok := value["query"].(string); ok {
+			return q
+		}
+	}
+	content, _ := json.Marshal(v)
+	return string(content)
+}
+
+func docPanel(p *sdk.Panel, rules map[string]recordingrules.RecordingRule) DocPanel {
+	res := DocPanel{Title: p.Title, Type: p.Type, Queries: []DocQuery{}, Links: docLinks(p.Links)}
+	if p.Description != nil {
+		res.Description = *p.Description
+	}
+	targets := p.GetTargets()
+	if targets == nil {
+		return res
+	}
+	for i, t := range *targets {
+		if t.Hide {
+			continue
+		}
+		q := DocQuery{RefID: targetName(t, i), Expr: t.Expr, Legend: t.LegendFormat}
+		if q.Expr == "" {
+			q.Expr = t.RawSql
+		}
+		if ds := t.Datasource; ds != nil {
+			q.Datasource = ds.UID
+			if q.Datasource == "" {
+				q.Datasource = ds.LegacyName
+			}
+		}
+		for _, name := range referencedMetrics(t.Expr) {
+			if rule, ok := rules[name]; ok {
+				q.Recorded = append(q.Recorded, rule)
+			}
+		}
+		res.Queries = append(res.Queries, q)
+	}
+	return res
+}
+
+func dashboardDoc(b dashboard.Builder, folder string, rules map[string]recordingrules.RecordingRule) DashboardDoc {
+	board := b.Internal()
+	res := DashboardDoc{
+		UID:       board.UID,
+		Title:     board.Title,
+		Folder:    folder,
+		Tags:      board.Tags,
+		Links:     docLinks(board.Links),
+		Variables: []DocVariable{},
+		Rows:      []DocRow{},
+	}
+	for _, v := range board.Templating.List {
+		res.Variables = append(res.Variables, DocVariable{
+			Name:    v.Name,
+			Label:   v.Label,
+			Type:    v.Type,
+			Query:   docValue(v.Query),
+			Default: docValue(v.Current.Value),
+		})
+	}
+	// panels without row come first, every row panel starts a new DocRow
+	current := DocRow{Panels: []DocPanel{}}
+	for _, p := range board.Panels {
+		if p.Type != "row" {
+			current.Panels = append(current.Panels, docPanel(p, rules))
+			continue
+		}
+		if current.Title != "" || len(current.Panels) > 0 {
+			res.Rows = append(res.Rows, current)
+		}
+		current = DocRow{Title: p.Title, Panels: []DocPanel{}}
+		if p.RowPanel != nil {
+			for i := range p.RowPanel.Panels {
+				current.Panels = append(current.Panels, docPanel(&p.RowPanel.Panels[i], rules))
+			}
+		}
+	}
+	if current.Title != "" || len(current.Panels) > 0 {
+		res.Rows = append(res.Rows, current)
+	}
+	for _, row := range board.Rows {
+		docRow := DocRow{Title: row.Title, Panels: []DocPanel{}}
+		for i := range row.Panels {
+			docRow.Panels = append(docRow.Panels, docPanel(&row.Panels[i], rules))
+		}
+		res.Rows = append(res.Rows, docRow)
+	}
+	return res
+}
+
+// Docs runs the Creator and describes every dashboard. Queries using a rule of a registered RecodingMap
+// carry the original expression
+func (r *Runner) Docs(in DocsOptions) ([]DashboardDoc, error) {
+	board, err := r.Dashboards(in.CreatorInput, in.Filter)
+	if err != nil {
+		return nil, err
+	}
+	rules := map[string]recordingrules.RecordingRule{}
+	for _, m := range r.RecordingMaps {
+		for _, rule := range m.Rules() {
+			rules[rule.Name] = rule
+		}
+	}
+	res := make([]DashboardDoc, 0, len(board))
+	for _, b := range board {
+		res = append(res, dashboardDoc(b, in.FolderName, rules))
+	}
+	return res, nil
+}
+
+// markdownCell escapes a value for a markdown table cell
+func markdownCell(value string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(value, "|", `\|`), "\n", " ")
+}
+
+var docsMarkdown = template.Must(template.New("docs.md").Funcs(template.FuncMap{"cell": markdownCell}).Parse(docsMarkdownTmpl))
+
+var docsHTML = htmlTemplate.Must(htmlTemplate.New("docs.html").Parse(docsHTMLTmpl))
+
+// RenderMarkdown writes the documentation of one dashboard as markdown
+func RenderMarkdown(w io.Writer, doc DashboardDoc) error {
+	return docsMarkdown.Execute(w, doc)
+}
+
+// RenderHTML writes the documentation of one dashboard as standalone html page
+func RenderHTML(w io.Writer, doc DashboardDoc) error {
+	return docsHTML.Execute(w, doc)
+}
+
+func writeDoc(filename string, doc DashboardDoc, render func(io.Writer, DashboardDoc) error) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("Error creating %s: %w", filename, err)
+	}
+	defer f.Close()
+	return render(f, doc)
+}
+
+func (r *Runner) docsAction(c *cli.Context) error {
+	docs, err := r.Docs(DocsOptions{CreatorInput: r.CreatorInput(c), Filter: FilterFromValues(c)})
+	if err != nil {
+		return err
+	}
+	dir := c.String(CliDocsDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		filename := path.Join(dir, doc.UID+".md")
+		if err := writeDoc(filename, doc, RenderMarkdown); err != nil {
+			return err
+		}
+		r.logger().Info("Documentation written", "uid", doc.UID, "file", filename)
+		if c.Bool(CliDocsHTML) {
+			filename = path.Join(dir, doc.UID+".html")
+			if err := writeDoc(filename, doc, RenderHTML); err != nil {
+				return err
+			}
+			r.logger().Info("Documentation written", "uid", doc.UID, "file", filename)
+		}
+	}
+	return nil
+}
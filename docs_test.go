@@ -0,0 +1,80 @@
+package grabanaclistarter
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/K-Phoen/grabana/dashboard"
+	"github.com/K-Phoen/grabana/row"
+	"github.com/K-Phoen/grabana/target/prometheus"
+	"github.com/K-Phoen/grabana/timeseries"
+	"github.com/K-Phoen/grabana/variable/custom"
+	"github.com/fasibio/grabana_cli_starter/recordingrules"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// assertGoldenFile compares got with testdata/name, -update rewrites the file
+func assertGoldenFile(t *testing.T, name string, got []byte) {
+	t.Helper()
+	filename := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(filename, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("golden file %s missing, run go test -update: %v", filename, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from %s, run go test -update to accept:\n%s", name, filename, got)
+	}
+}
+
+func TestDocs(t *testing.T) {
+	rules := recordingrules.NewRecordingMap(false)
+	rules.AppendRule("job:errors:rate5m", `sum by (job) (rate(http_errors_total[5m]))`)
+	r := &Runner{
+		RecordingMaps: []*recordingrules.RecodingMap{&rules},
+		Dashboard: func(in CreatorInput) ([]dashboard.Builder, error) {
+			b, err := dashboard.New("API",
+				dashboard.UID("api"),
+				dashboard.Tags([]string{"team", "owner:sre"}),
+				dashboard.VariableAsCustom("env", custom.Values(map[string]string{"prod": "prod"}), custom.Default("prod")),
+				dashboard.Row("Traffic",
+					row.WithTimeSeries("requests",
+						timeseries.Description("requests | errors per job"),
+						rules.WithTimeSeries("job:requests:rate5m", `sum by (job) (rate(http_requests_total{env="$env"}[5m]))`),
+					),
+					row.WithTimeSeries("error ratio",
+						timeseries.WithPrometheusTarget(`sum(job:errors:rate5m) / sum(job:requests:rate5m)`, prometheus.Legend("{{ job }}")),
+					),
+				),
+			)
+			return []dashboard.Builder{b}, err
+		},
+	}
+	docs, err := r.Docs(DocsOptions{CreatorInput: CreatorInput{FolderName: "services"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 1 {
+		t.Fatalf("got %d docs, want 1", len(docs))
+	}
+	renderers := map[string]func(io.Writer, DashboardDoc) error{"docs.golden.md": RenderMarkdown, "docs.golden.html": RenderHTML}
+	for name, render := range renderers {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := render(&buf, docs[0]); err != nil {
+				t.Fatal(err)
+			}
+			assertGoldenFile(t, name, buf.Bytes())
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package grabanaclistarter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cryptvault-cloud/helper"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+type EnvironmentValueType string
+
+const (
+	EnvironmentString EnvironmentValueType = "string"
+	EnvironmentInt    EnvironmentValueType = "int"
+	EnvironmentFloat  EnvironmentValueType = "float"
+	EnvironmentBool   EnvironmentValueType = "bool"
+)
+
+// EnvironmentVariable declares a value the DashboardCreator expects per environment
+type EnvironmentVariable struct {
+	Name     string
+	Type     EnvironmentValueType
+	Usage    string
+	Default  string
+	Required bool
+	// Allowed restricts the value to one of the given values if not empty
+	Allowed []string
+}
+
+func (v EnvironmentVariable) validate(value string) error {
+	var err error
+	switch v.Type {
+	case EnvironmentInt:
+		_, err = strconv.Atoi(value)
+	case EnvironmentFloat:
+		_, err = strconv.ParseFloat(value, 64)
+	case EnvironmentBool:
+		_, err = strconv.ParseBool(value)
+	case EnvironmentString, "":
+	default:
+		return fmt.Errorf("Unknown type %s for environment variable %s", v.Type, v.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("Environment variable %s=%q is not of type %s", v.Name, value, v.Type)
+	}
+	if len(v.Allowed) > 0 && !helper.Includes(v.Allowed, func(a string) bool { return a == value }) {
+		return fmt.Errorf("Environment variable %s=%q must be one of %s", v.Name, value, strings.Join(v.Allowed, ", "))
+	}
+	return nil
+}
+
+// Environment holds the validated per environment values passed into the DashboardCreator
+type Environment struct {
+	values map[string]string
+}
+
+// NewEnvironment validates values against schema and fills in the defaults.
+// Without schema every value is accepted as string.
+func NewEnvironment(schema []EnvironmentVariable, values map[string]string) (Environment, error) {
+	env := Environment{values: map[string]string{}}
+	if len(schema) == 0 {
+		for k, v := range values {
+			env.values[k] = v
+		}
+		return env, nil
+	}
+	declared := map[string]EnvironmentVariable{}
+	for _, v := range schema {
+		declared[v.Name] = v
+	}
+	err := errors.Join(nil)
+	for k := range values {
+		if _, ok := declared[k]; !ok {
+			err = errors.Join(err, fmt.Errorf("Environment variable %s is not declared", k))
+		}
+	}
+	for _, v := range schema {
+		value, ok := values[v.Name]
+		if !ok {
+			if v.Required {
+				err = errors.Join(err, fmt.Errorf("Environment variable %s is required", v.Name))
+				continue
+			}
+			if v.Default == "" {
+				continue
+			}
+			value = v.Default
+		}
+		if tmpErr := v.validate(value); tmpErr != nil {
+			err = errors.Join(err, tmpErr)
+			continue
+		}
+		env.values[v.Name] = value
+	}
+	return env, err
+}
+
+func (e Environment) Lookup(key string) (string, bool) {
+	v, ok := e.values[key]
+	return v, ok
+}
+
+func (e Environment) String(key string) string {
+	return e.values[key]
+}
+
+func (e Environment) Int(key string) int {
+	v, _ := strconv.Atoi(e.values[key])
+	return v
+}
+
+func (e Environment) Float(key string) float64 {
+	v, _ := strconv.ParseFloat(e.values[key], 64)
+	return v
+}
+
+func (e Environment) Bool(key string) bool {
+	v, _ := strconv.ParseBool(e.values[key])
+	return v
+}
+
+// Keys returns all set keys sorted
+func (e Environment) Keys() []string {
+	keys := make([]string, 0, len(e.values))
+	for k := range e.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type environmentKey struct{}
+
+// GetEnvironment returns the Environment parsed by the dashboard command
+func GetEnvironment(c *cli.Context) Environment {
+	if c.Context != nil {
+		if env, ok := c.Context.Value(environmentKey{}).(Environment); ok {
+			return env
+		}
+	}
+	return Environment{values: map[string]string{}}
+}
+
+func EnvironmentSchema(vars ...EnvironmentVariable) Option {
+	return func(runner *Runner, app *cli.App) error {
+		runner.EnvironmentSchema = append(runner.EnvironmentSchema, vars...)
+		return nil
+	}
+}
+
+// parseEnvironment merges --var-file and --var values (--var wins) and validates them against the schema
+func (r *Runner) parseEnvironment(c *cli.Context) (Environment, error) {
+	values := map[string]string{}
+	if file := c.String(CliVarFile); file != "" {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return Environment{}, fmt.Errorf("Error reading var file: %w", err)
+		}
+		fileValues := map[string]string{}
+		if err := yaml.Unmarshal(content, &fileValues); err != nil {
+			return Environment{}, fmt.Errorf("Error parsing var file %s: %w", file, err)
+		}
+		for k, v := range fileValues {
+			values[k] = v
+		}
+	}
+	for _, kv := range c.StringSlice(CliVar) {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return Environment{}, fmt.Errorf("Invalid --%s %q, expected key=value", CliVar, kv)
+		}
+		values[strings.TrimSpace(k)] = v
+	}
+	return NewEnvironment(r.EnvironmentSchema, values)
+}
+
+func (r *Runner) withEnvironment(c *cli.Context) error {
+	env, err := r.parseEnvironment(c)
+	if err != nil {
+		return err
+	}
+	r.Environment = env
+	if c.Context == nil {
+		c.Context = context.Background()
+	}
+	c.Context = ContextWithEnvironment(c.Context, env)
+	return nil
+}
+
+// ContextWithEnvironment stores env for GetEnvironment, e.g. to call a DashboardCreator without the cli
+func ContextWithEnvironment(ctx context.Context, env Environment) context.Context {
+	return context.WithValue(ctx, environmentKey{}, env)
+}
+
+func printEnvironment(env Environment) {
+	keys := env.Keys()
+	if len(keys) == 0 {
+		return
+	}
+	fmt.Println("Environment:")
+	for _, k := range keys {
+		fmt.Printf("\t%s = %s\n", k, env.String(k))
+	}
+}
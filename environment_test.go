@@ -0,0 +1,146 @@
+package grabanaclistarter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+var testSchema = []EnvironmentVariable{
+	{Name: "stage", Required: true, Allowed: []string{"dev", "prod"}},
+	{Name: "replicas", Type: EnvironmentInt, Default: "2"},
+	{Name: "ratio", Type: EnvironmentFloat},
+	{Name: "alerts", Type: EnvironmentBool, Default: "true"},
+}
+
+func TestNewEnvironment(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  []EnvironmentVariable
+		values  map[string]string
+		want    map[string]string
+		wantErr []string
+	}{
+		{
+			name:   "without schema",
+			values: map[string]string{"any": "thing"},
+			want:   map[string]string{"any": "thing"},
+		},
+		{
+			name:   "defaults",
+			schema: testSchema,
+			values: map[string]string{"stage": "dev"},
+			want:   map[string]string{"stage": "dev", "replicas": "2", "alerts": "true"},
+		},
+		{
+			name:   "values win over defaults",
+			schema: testSchema,
+			values: map[string]string{"stage": "prod", "replicas": "5", "ratio": "0.5", "alerts": "false"},
+			want:   map[string]string{"stage": "prod", "replicas": "5", "ratio": "0.5", "alerts": "false"},
+		},
+		{
+			name:    "required missing",
+			schema:  testSchema,
+			values:  map[string]string{},
+			wantErr: []string{"Environment variable stage is required"},
+		},
+		{
+			name:    "not declared",
+			schema:  testSchema,
+			values:  map[string]string{"stage": "dev", "region": "eu"},
+			wantErr: []string{"Environment variable region is not declared"},
+		},
+		{
+			name:   "wrong types",
+			schema: testSchema,
+			values: map[string]string{"stage": "dev", "replicas": "two", "ratio": "half", "alerts": "maybe"},
+			wantErr: []string{
+				`Environment variable replicas="two" is not of type int`,
+				`Environment variable ratio="half" is not of type float`,
+				`Environment variable alerts="maybe" is not of type bool`,
+			},
+		},
+		{
+			name:    "not allowed",
+			schema:  testSchema,
+			values:  map[string]string{"stage": "test"},
+			wantErr: []string{`Environment variable stage="test" must be one of dev, prod`},
+		},
+		{
+			name:    "unknown type",
+			schema:  []EnvironmentVariable{{Name: "when", Type: "date"}},
+			values:  map[string]string{"when": "today"},
+			wantErr: []string{"Unknown type date for environment variable when"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, err := NewEnvironment(tt.schema, tt.values)
+			if len(tt.wantErr) > 0 {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				for _, want := range tt.wantErr {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("error %q does not contain %q", err, want)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(env.values, tt.want) {
+				t.Errorf("got %v, want %v", env.values, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvironmentAccessors(t *testing.T) {
+	env, err := NewEnvironment(testSchema, map[string]string{"stage": "prod", "replicas": "3", "ratio": "0.25"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.String("stage") != "prod" || env.Int("replicas") != 3 || env.Float("ratio") != 0.25 || !env.Bool("alerts") {
+		t.Errorf("got stage %s, replicas %d, ratio %g, alerts %t", env.String("stage"), env.Int("replicas"), env.Float("ratio"), env.Bool("alerts"))
+	}
+	if _, ok := env.Lookup("missing"); ok {
+		t.Error("Lookup found an unset key")
+	}
+	if env.Int("missing") != 0 || env.Bool("missing") {
+		t.Error("unset keys have to return the zero value")
+	}
+	if got := env.Keys(); !reflect.DeepEqual(got, []string{"alerts", "ratio", "replicas", "stage"}) {
+		t.Errorf("got keys %v", got)
+	}
+}
+
+func TestParseEnvironment(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "vars.yaml")
+	if err := os.WriteFile(file, []byte("stage: dev\nreplicas: \"4\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	flags := []cli.Flag{&cli.StringFlag{Name: CliVarFile}, &cli.StringSliceFlag{Name: CliVar}}
+	r := &Runner{EnvironmentSchema: testSchema}
+
+	env, err := r.parseEnvironment(flagContext(t, flags, "--var-file", file, "--var", "stage=prod", "--var", "ratio=1.5"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"stage": "prod", "replicas": "4", "ratio": "1.5", "alerts": "true"}
+	if !reflect.DeepEqual(env.values, want) {
+		t.Errorf("got %v, want %v", env.values, want)
+	}
+
+	if _, err := r.parseEnvironment(flagContext(t, flags, "--var", "stage")); err == nil {
+		t.Error("expected an error for --var without value")
+	}
+	if _, err := r.parseEnvironment(flagContext(t, flags, "--var-file", filepath.Join(t.TempDir(), "missing.yaml"))); err == nil {
+		t.Error("expected an error for a missing var file")
+	}
+}
@@ -0,0 +1,179 @@
+package grabanaclistarter
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/K-Phoen/grabana/dashboard"
+	"github.com/cryptvault-cloud/helper"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	CliOnly       CliValues = "only"
+	CliExclude    CliValues = "exclude"
+	CliOnlyTag    CliValues = "only-tag"
+	CliExcludeTag CliValues = "exclude-tag"
+)
+
+func filterFlags(appName string) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:    CliOnly,
+			EnvVars: []string{GetFlagEnvByFlagName(CliOnly, appName)},
+			Usage:   "only dashboards whose uid or title matches the glob (repeatable)",
+		},
+		&cli.StringSliceFlag{
+			Name:    CliExclude,
+			EnvVars: []string{GetFlagEnvByFlagName(CliExclude, appName)},
+			Usage:   "skip dashboards whose uid or title matches the glob (repeatable)",
+		},
+		&cli.StringSliceFlag{
+			Name:    CliOnlyTag,
+			EnvVars: []string{GetFlagEnvByFlagName(CliOnlyTag, appName)},
+			Usage:   "only dashboards with one of the tags (repeatable)",
+		},
+		&cli.StringSliceFlag{
+			Name:    CliExcludeTag,
+			EnvVars: []string{GetFlagEnvByFlagName(CliExcludeTag, appName)},
+			Usage:   "skip dashboards with one of the tags (repeatable)",
+		},
+	}
+}
+
+// Filter selects dashboards by uid/title glob and tag. The zero Filter selects everything
+type Filter struct {
+	Only        []string
+	Exclude     []string
+	OnlyTags    []string
+	ExcludeTags []string
+}
+
+func FilterFromValues(v Values) Filter {
+	if v == nil {
+		return Filter{}
+	}
+	return Filter{
+		Only:        v.StringSlice(CliOnly),
+		Exclude:     v.StringSlice(CliExclude),
+		OnlyTags:    v.StringSlice(CliOnlyTag),
+		ExcludeTags: v.StringSlice(CliExcludeTag),
+	}
+}
+
+func matchGlobs(globs []string, values ...string) bool {
+	for _, g := range globs {
+		for _, v := range values {
+			if ok, _ := path.Match(g, v); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasAnyTag(tags, wanted []string) bool {
+	for _, w := range wanted {
+		if helper.Includes(tags, func(t string) bool { return strings.EqualFold(t, w) }) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks all globs
+func (f Filter) Validate() error {
+	for _, g := range append(append([]string{}, f.Only...), f.Exclude...) {
+		if _, err := path.Match(g, ""); err != nil {
+			return fmt.Errorf("Invalid filter %q: %w", g, err)
+		}
+	}
+	return nil
+}
+
+func (f Filter) Match(b dashboard.Builder) bool {
+	board := b.Internal()
+	if len(f.Only) > 0 && !matchGlobs(f.Only, board.UID, board.Title) {
+		return false
+	}
+	if matchGlobs(f.Exclude, board.UID, board.Title) {
+		return false
+	}
+	if len(f.OnlyTags) > 0 && !hasAnyTag(board.Tags, f.OnlyTags) {
+		return false
+	}
+	return !hasAnyTag(board.Tags, f.ExcludeTags)
+}
+
+func (f Filter) Apply(boards []dashboard.Builder) []dashboard.Builder {
+	res := make([]dashboard.Builder, 0, len(boards))
+	for _, b := range boards {
+		if f.Match(b) {
+			res = append(res, b)
+		}
+	}
+	return res
+}
+
+// Dashboards runs the Creator and returns only the dashboards selected by filter
+func (r *Runner) Dashboards(in CreatorInput, filter Filter) ([]dashboard.Builder, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	board, err := r.Build(in)
+	if err != nil {
+		return nil, err
+	}
+	return filter.Apply(board), nil
+}
+
+type ListEntry struct {
+	UID      string   `json:"uid" yaml:"uid"`
+	Title    string   `json:"title" yaml:"title"`
+	Tags     []string `json:"tags" yaml:"tags"`
+	Selected bool     `json:"selected" yaml:"selected"`
+}
+
+// List returns every dashboard of the Creator and whether filter selects it
+func (r *Runner) List(in CreatorInput, filter Filter) ([]ListEntry, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	board, err := r.Build(in)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]ListEntry, 0, len(board))
+	for _, b := range board {
+		res = append(res, ListEntry{
+			UID:      b.Internal().UID,
+			Title:    b.Internal().Title,
+			Tags:     b.Internal().Tags,
+			Selected: filter.Match(b),
+		})
+	}
+	return res, nil
+}
+
+func (r *Runner) listAction(c *cli.Context) error {
+	entries, err := r.List(r.CreatorInput(c), FilterFromValues(c))
+	if err != nil {
+		return err
+	}
+	if format := c.String(CliOutput); format != OutputText {
+		return writeStructured(os.Stdout, format, entries)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SELECTED\tUID\tTITLE\tTAGS")
+	for _, e := range entries {
+		selected := ""
+		if e.Selected {
+			selected = "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", selected, e.UID, e.Title, strings.Join(e.Tags, ","))
+	}
+	return w.Flush()
+}
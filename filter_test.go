@@ -0,0 +1,84 @@
+package grabanaclistarter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/K-Phoen/grabana/dashboard"
+)
+
+func filterBoards(t *testing.T) []dashboard.Builder {
+	t.Helper()
+	res := []dashboard.Builder{}
+	for _, b := range []struct {
+		uid, title string
+		tags       []string
+	}{
+		{uid: "api-latency", title: "API Latency", tags: []string{"api", "slo"}},
+		{uid: "api-errors", title: "API Errors", tags: []string{"api"}},
+		{uid: "db-load", title: "Database", tags: []string{"DB"}},
+	} {
+		board, err := dashboard.New(b.title, dashboard.UID(b.uid), dashboard.Tags(b.tags))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, board)
+	}
+	return res
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  Filter
+		want    []string
+		wantErr bool
+	}{
+		{name: "zero filter", want: []string{"api-latency", "api-errors", "db-load"}},
+		{name: "only uid glob", filter: Filter{Only: []string{"api-*"}}, want: []string{"api-latency", "api-errors"}},
+		{name: "only title", filter: Filter{Only: []string{"Database"}}, want: []string{"db-load"}},
+		{name: "only unknown uid", filter: Filter{Only: []string{"unknown"}}, want: []string{}},
+		{name: "exclude", filter: Filter{Exclude: []string{"api-errors"}}, want: []string{"api-latency", "db-load"}},
+		{name: "exclude unknown uid", filter: Filter{Exclude: []string{"unknown"}}, want: []string{"api-latency", "api-errors", "db-load"}},
+		{name: "only and exclude", filter: Filter{Only: []string{"api-*"}, Exclude: []string{"*errors"}}, want: []string{"api-latency"}},
+		{name: "exclude wins over only", filter: Filter{Only: []string{"db-load"}, Exclude: []string{"db-load"}}, want: []string{}},
+		{name: "only tag", filter: Filter{OnlyTags: []string{"slo", "db"}}, want: []string{"api-latency", "db-load"}},
+		{name: "exclude tag", filter: Filter{ExcludeTags: []string{"API"}}, want: []string{"db-load"}},
+		{name: "only tag and exclude tag", filter: Filter{OnlyTags: []string{"api"}, ExcludeTags: []string{"slo"}}, want: []string{"api-errors"}},
+		{name: "glob and tag", filter: Filter{Only: []string{"api-*"}, OnlyTags: []string{"slo"}}, want: []string{"api-latency"}},
+		{name: "invalid only glob", filter: Filter{Only: []string{"api-["}}, wantErr: true},
+		{name: "invalid exclude glob", filter: Filter{Exclude: []string{"["}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.filter.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, wantErr %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := []string{}
+			for _, b := range tt.filter.Apply(filterBoards(t)) {
+				got = append(got, b.Internal().UID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDashboardsRejectsInvalidFilter(t *testing.T) {
+	built := false
+	r := &Runner{Dashboard: func(in CreatorInput) ([]dashboard.Builder, error) {
+		built = true
+		return filterBoards(t), nil
+	}}
+	if _, err := r.Dashboards(CreatorInput{}, Filter{Only: []string{"["}}); err == nil {
+		t.Error("expected an error")
+	}
+	if built {
+		t.Error("the Creator ran with an invalid filter")
+	}
+}
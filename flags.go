@@ -0,0 +1,218 @@
+package grabanaclistarter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cryptvault-cloud/helper"
+	"github.com/urfave/cli/v2"
+)
+
+// CommandCreator builds a custom command. The Runner is the same one used by the build in commands
+type CommandCreator func(runner *Runner) *cli.Command
+
+// Flags adds extra flags to the command found by path (e.g. "dashboard" or "dev run").
+// The values are readable inside the DashboardCreator through the cli.Context
+func Flags(path string, flags ...cli.Flag) Option {
+	return func(runner *Runner, app *cli.App) error {
+		cmd, err := findCommand(app, path)
+		if err != nil {
+			return err
+		}
+		cmd.Flags = append(cmd.Flags, flags...)
+		return nil
+	}
+}
+
+// DashboardFlags adds extra flags to the dashboard command
+func DashboardFlags(flags ...cli.Flag) Option {
+	return Flags("dashboard", flags...)
+}
+
+// DevFlags adds extra flags to the dev command
+func DevFlags(flags ...cli.Flag) Option {
+	return Flags("dev", flags...)
+}
+
+// Command adds a custom command below path. An empty path adds a top level command
+func Command(path string, create CommandCreator) Option {
+	return func(runner *Runner, app *cli.App) error {
+		newCmd := create(runner)
+		if newCmd == nil {
+			return fmt.Errorf("CommandCreator for %q returned no command", path)
+		}
+		if strings.TrimSpace(path) == "" {
+			if app.Command(newCmd.Name) != nil {
+				return fmt.Errorf("Command %s already exist", newCmd.Name)
+			}
+			app.Commands = append(app.Commands, newCmd)
+			return nil
+		}
+		cmd, err := findCommand(app, path)
+		if err != nil {
+			return err
+		}
+		if cmd.Command(newCmd.Name) != nil {
+			return fmt.Errorf("Command %s %s already exist", path, newCmd.Name)
+		}
+		cmd.Subcommands = append(cmd.Subcommands, newCmd)
+		return nil
+	}
+}
+
+// DefaultFlagValue sets the default of flag name at the command found by path.
+// value has to match the flag type (string, bool, int, []string, time.Duration ...) or be a parsable string.
+// A flag with default is no longer required
+func DefaultFlagValue(path, name string, value any) Option {
+	return func(runner *Runner, app *cli.App) error {
+		cmd, err := findCommand(app, path)
+		if err != nil {
+			return err
+		}
+		f := findFlag(cmd.Flags, name)
+		if f == nil {
+			return fmt.Errorf("Flag %s not found at command %s", name, path)
+		}
+		return setFlagDefault(f, value)
+	}
+}
+
+// findCommand resolves a space separated command path like "dev run"
+func findCommand(app *cli.App, path string) (*cli.Command, error) {
+	names := strings.Fields(path)
+	if len(names) == 0 {
+		return nil, fmt.Errorf("Empty command path")
+	}
+	cmd := app.Command(names[0])
+	for i := 1; cmd != nil && i < len(names); i++ {
+		cmd = cmd.Command(names[i])
+	}
+	if cmd == nil {
+		return nil, fmt.Errorf("Command %q not found", path)
+	}
+	return cmd, nil
+}
+
+func findFlag(flags []cli.Flag, name string) cli.Flag {
+	for _, f := range flags {
+		if helper.Includes(f.Names(), func(n string) bool { return n == name }) {
+			return f
+		}
+	}
+	return nil
+}
+
+func setFlagDefault(f cli.Flag, value any) error {
+	var err error
+	switch flag := f.(type) {
+	case *cli.StringFlag:
+		flag.Value = fmt.Sprint(value)
+		flag.Required = false
+	case *cli.PathFlag:
+		flag.Value = fmt.Sprint(value)
+		flag.Required = false
+	case *cli.BoolFlag:
+		flag.Value, err = asBool(value)
+		flag.Required = false
+	case *cli.IntFlag:
+		flag.Value, err = asInt(value)
+		flag.Required = false
+	case *cli.Int64Flag:
+		var v int
+		v, err = asInt(value)
+		flag.Value = int64(v)
+		flag.Required = false
+	case *cli.UintFlag:
+		var v int
+		v, err = asInt(value)
+		flag.Value = uint(v)
+		flag.Required = false
+	case *cli.Float64Flag:
+		flag.Value, err = asFloat(value)
+		flag.Required = false
+	case *cli.DurationFlag:
+		flag.Value, err = asDuration(value)
+		flag.Required = false
+	case *cli.StringSliceFlag:
+		var v []string
+		v, err = asStringSlice(value)
+		flag.Value = cli.NewStringSlice(v...)
+		flag.Required = false
+	default:
+		return fmt.Errorf("Flag %s has unsupported type %T", f.Names()[0], f)
+	}
+	if err != nil {
+		return fmt.Errorf("Invalid default for flag %s: %w", f.Names()[0], err)
+	}
+	return nil
+}
+
+func asBool(value any) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(v)
+	}
+	return false, fmt.Errorf("%v is not a bool", value)
+}
+
+func asInt(value any) (int, error) {
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case uint:
+		return int(v), nil
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), nil
+		}
+	case string:
+		return strconv.Atoi(v)
+	}
+	return 0, fmt.Errorf("%v is not an int", value)
+}
+
+func asFloat(value any) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, fmt.Errorf("%v is not a float", value)
+}
+
+func asDuration(value any) (time.Duration, error) {
+	switch v := value.(type) {
+	case time.Duration:
+		return v, nil
+	case string:
+		return time.ParseDuration(v)
+	}
+	return 0, fmt.Errorf("%v is not a duration", value)
+}
+
+func asStringSlice(value any) ([]string, error) {
+	switch v := value.(type) {
+	case []string:
+		return v, nil
+	case string:
+		return []string{v}, nil
+	case []any:
+		res := make([]string, 0, len(v))
+		for _, e := range v {
+			res = append(res, fmt.Sprint(e))
+		}
+		return res, nil
+	}
+	return nil, fmt.Errorf("%v is not a list", value)
+}
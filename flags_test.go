@@ -0,0 +1,77 @@
+package grabanaclistarter
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+func TestSetFlagDefault(t *testing.T) {
+	tests := []struct {
+		name    string
+		flag    cli.Flag
+		value   any
+		get     func(c *cli.Context) any
+		want    any
+		wantErr bool
+	}{
+		{name: "string", flag: &cli.StringFlag{Name: "v", Required: true}, value: "prod", get: func(c *cli.Context) any { return c.String("v") }, want: "prod"},
+		{name: "string from int", flag: &cli.StringFlag{Name: "v"}, value: 3, get: func(c *cli.Context) any { return c.String("v") }, want: "3"},
+		{name: "path", flag: &cli.PathFlag{Name: "v", Required: true}, value: "/tmp/a", get: func(c *cli.Context) any { return c.Path("v") }, want: "/tmp/a"},
+		{name: "bool", flag: &cli.BoolFlag{Name: "v", Required: true}, value: true, get: func(c *cli.Context) any { return c.Bool("v") }, want: true},
+		{name: "bool from string", flag: &cli.BoolFlag{Name: "v"}, value: "true", get: func(c *cli.Context) any { return c.Bool("v") }, want: true},
+		{name: "bool invalid", flag: &cli.BoolFlag{Name: "v"}, value: 2, wantErr: true},
+		{name: "int", flag: &cli.IntFlag{Name: "v", Required: true}, value: 5, get: func(c *cli.Context) any { return c.Int("v") }, want: 5},
+		{name: "int from yaml float", flag: &cli.IntFlag{Name: "v"}, value: 5.0, get: func(c *cli.Context) any { return c.Int("v") }, want: 5},
+		{name: "int from string", flag: &cli.IntFlag{Name: "v"}, value: "7", get: func(c *cli.Context) any { return c.Int("v") }, want: 7},
+		{name: "int invalid", flag: &cli.IntFlag{Name: "v"}, value: 1.5, wantErr: true},
+		{name: "int64", flag: &cli.Int64Flag{Name: "v", Required: true}, value: 8, get: func(c *cli.Context) any { return c.Int64("v") }, want: int64(8)},
+		{name: "uint", flag: &cli.UintFlag{Name: "v", Required: true}, value: "9", get: func(c *cli.Context) any { return c.Uint("v") }, want: uint(9)},
+		{name: "float", flag: &cli.Float64Flag{Name: "v", Required: true}, value: 2, get: func(c *cli.Context) any { return c.Float64("v") }, want: 2.0},
+		{name: "float from string", flag: &cli.Float64Flag{Name: "v"}, value: "0.5", get: func(c *cli.Context) any { return c.Float64("v") }, want: 0.5},
+		{name: "float invalid", flag: &cli.Float64Flag{Name: "v"}, value: true, wantErr: true},
+		{name: "duration", flag: &cli.DurationFlag{Name: "v", Required: true}, value: time.Minute, get: func(c *cli.Context) any { return c.Duration("v") }, want: time.Minute},
+		{name: "duration from string", flag: &cli.DurationFlag{Name: "v"}, value: "30s", get: func(c *cli.Context) any { return c.Duration("v") }, want: 30 * time.Second},
+		{name: "duration invalid", flag: &cli.DurationFlag{Name: "v"}, value: "soon", wantErr: true},
+		{name: "string slice", flag: &cli.StringSliceFlag{Name: "v", Required: true}, value: []string{"a", "b"}, get: func(c *cli.Context) any { return c.StringSlice("v") }, want: []string{"a", "b"}},
+		{name: "string slice from yaml list", flag: &cli.StringSliceFlag{Name: "v"}, value: []any{"a", 1}, get: func(c *cli.Context) any { return c.StringSlice("v") }, want: []string{"a", "1"}},
+		{name: "string slice from string", flag: &cli.StringSliceFlag{Name: "v"}, value: "a", get: func(c *cli.Context) any { return c.StringSlice("v") }, want: []string{"a"}},
+		{name: "string slice invalid", flag: &cli.StringSliceFlag{Name: "v"}, value: 1, wantErr: true},
+		{name: "unsupported type", flag: &cli.TimestampFlag{Name: "v"}, value: "2024-01-01", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := setFlagDefault(tt.flag, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, wantErr %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if rf, ok := tt.flag.(cli.RequiredFlag); ok && rf.IsRequired() {
+				t.Error("flag with default is still required")
+			}
+			if got := tt.get(flagContext(t, []cli.Flag{tt.flag})); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultFlagValue(t *testing.T) {
+	app := &cli.App{Commands: []*cli.Command{{Name: "dev", Subcommands: []*cli.Command{{Name: "run", Flags: []cli.Flag{&cli.StringFlag{Name: "datasource"}}}}}}}
+	if err := DefaultFlagValue("dev run", "datasource", "prom")(&Runner{}, app); err != nil {
+		t.Fatal(err)
+	}
+	if got := app.Commands[0].Subcommands[0].Flags[0].(*cli.StringFlag).Value; got != "prom" {
+		t.Errorf("got default %q, want prom", got)
+	}
+	if err := DefaultFlagValue("dev run", "missing", "x")(&Runner{}, app); err == nil {
+		t.Error("expected an error for an unknown flag")
+	}
+	if err := DefaultFlagValue("dev stop", "datasource", "x")(&Runner{}, app); err == nil {
+		t.Error("expected an error for an unknown command")
+	}
+}
@@ -0,0 +1,112 @@
+package grabanatest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/K-Phoen/grabana/dashboard"
+	"github.com/K-Phoen/sdk"
+)
+
+// FindDashboard returns the dashboard with the uid or fails the test
+func FindDashboard(t testing.TB, boards []dashboard.Builder, uid string) dashboard.Builder {
+	t.Helper()
+	uids := []string{}
+	for _, b := range boards {
+		if b.Internal().UID == uid {
+			return b
+		}
+		uids = append(uids, b.Internal().UID)
+	}
+	t.Fatalf("grabanatest: no dashboard with uid %s, found %s", uid, strings.Join(uids, ", "))
+	return dashboard.Builder{}
+}
+
+// panels returns all panels of the dashboard including the ones of rows
+func panels(board *sdk.Board) []*sdk.Panel {
+	res := []*sdk.Panel{}
+	for _, p := range board.Panels {
+		if p.Type != "row" {
+			res = append(res, p)
+			continue
+		}
+		if p.RowPanel != nil {
+			for i := range p.RowPanel.Panels {
+				res = append(res, &p.RowPanel.Panels[i])
+			}
+		}
+	}
+	for _, row := range board.Rows {
+		for i := range row.Panels {
+			res = append(res, &row.Panels[i])
+		}
+	}
+	return res
+}
+
+// FindPanel returns the panel with the title or fails the test
+func FindPanel(t testing.TB, b dashboard.Builder, title string) *sdk.Panel {
+	t.Helper()
+	for _, p := range panels(b.Internal()) {
+		if p.Title == title {
+			return p
+		}
+	}
+	t.Fatalf("grabanatest: dashboard %s has no panel %q", b.Internal().UID, title)
+	return nil
+}
+
+// AssertPanelQueryContains fails if no query of the panel contains substr
+func AssertPanelQueryContains(t testing.TB, b dashboard.Builder, panelTitle, substr string) {
+	t.Helper()
+	p := FindPanel(t, b, panelTitle)
+	queries := []string{}
+	if targets := p.GetTargets(); targets != nil {
+		for _, target := range *targets {
+			for _, q := range []string{target.Expr, target.RawSql, target.Query, target.Target} {
+				if q == "" {
+					continue
+				}
+				if strings.Contains(q, substr) {
+					return
+				}
+				queries = append(queries, q)
+			}
+		}
+	}
+	t.Errorf("grabanatest: no query of panel %q contains %q, queries: %q", panelTitle, substr, queries)
+}
+
+// AssertVariableDefault fails if the dashboard variable does not exist or has another default value
+func AssertVariableDefault(t testing.TB, b dashboard.Builder, name, value string) {
+	t.Helper()
+	for _, v := range b.Internal().Templating.List {
+		if v.Name != name {
+			continue
+		}
+		current := fmt.Sprint(v.Current.Value)
+		if values, ok := v.Current.Value.([]string); ok {
+			current = strings.Join(values, ",")
+		}
+		if current != value {
+			t.Errorf("grabanatest: variable %s has default %q, want %q", name, current, value)
+		}
+		return
+	}
+	t.Errorf("grabanatest: dashboard %s has no variable %s", b.Internal().UID, name)
+}
+
+// AssertTags fails if the dashboard misses one of the tags
+func AssertTags(t testing.TB, b dashboard.Builder, tags ...string) {
+	t.Helper()
+	for _, tag := range tags {
+		found := false
+		for _, existing := range b.Internal().Tags {
+			found = found || existing == tag
+		}
+		if !found {
+			t.Errorf("grabanatest: dashboard %s has no tag %s, tags: %q", b.Internal().UID, tag, b.Internal().Tags)
+		}
+	}
+}
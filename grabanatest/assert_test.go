@@ -0,0 +1,120 @@
+package grabanatest_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/K-Phoen/grabana/dashboard"
+	"github.com/K-Phoen/grabana/row"
+	"github.com/K-Phoen/grabana/timeseries"
+	"github.com/K-Phoen/grabana/variable/custom"
+	"github.com/fasibio/grabana_cli_starter/grabanatest"
+)
+
+// recorder collects the failures of the grabanatest helpers instead of failing the test
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+// record runs fn with a recorder and returns its failures
+func record(t *testing.T, fn func(tb testing.TB)) []string {
+	r := &recorder{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(r)
+	}()
+	<-done
+	return r.failures
+}
+
+func assertFailures(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got failures %q, want %d", got, len(want))
+	}
+	for i := range want {
+		if !strings.Contains(got[i], want[i]) {
+			t.Errorf("failure %q does not contain %q", got[i], want[i])
+		}
+	}
+}
+
+func assertBoard(t *testing.T) dashboard.Builder {
+	t.Helper()
+	b, err := dashboard.New("Assert",
+		dashboard.UID("assert"),
+		dashboard.Tags([]string{"team", "owner:sre"}),
+		dashboard.VariableAsCustom("env", custom.Values(map[string]string{"prod": "prod", "dev": "dev"}), custom.Default("prod")),
+		dashboard.Row("r", row.WithTimeSeries("errors", timeseries.WithPrometheusTarget(`rate(errors_total{env="$env"}[5m])`))),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestAssertHelpers(t *testing.T) {
+	b := assertBoard(t)
+	tests := []struct {
+		name string
+		fn   func(tb testing.TB)
+		want []string
+	}{
+		{name: "dashboard found", fn: func(tb testing.TB) { grabanatest.FindDashboard(tb, []dashboard.Builder{b}, "assert") }},
+		{name: "dashboard missing", fn: func(tb testing.TB) { grabanatest.FindDashboard(tb, []dashboard.Builder{b}, "other") }, want: []string{"no dashboard with uid other, found assert"}},
+		{name: "panel missing", fn: func(tb testing.TB) { grabanatest.FindPanel(tb, b, "latency") }, want: []string{`has no panel "latency"`}},
+		{name: "query contains", fn: func(tb testing.TB) { grabanatest.AssertPanelQueryContains(tb, b, "errors", "errors_total") }},
+		{name: "query differs", fn: func(tb testing.TB) { grabanatest.AssertPanelQueryContains(tb, b, "errors", "latency") }, want: []string{`no query of panel "errors" contains "latency"`}},
+		{name: "variable default", fn: func(tb testing.TB) { grabanatest.AssertVariableDefault(tb, b, "env", "prod") }},
+		{name: "variable other default", fn: func(tb testing.TB) { grabanatest.AssertVariableDefault(tb, b, "env", "dev") }, want: []string{`variable env has default "prod", want "dev"`}},
+		{name: "variable missing", fn: func(tb testing.TB) { grabanatest.AssertVariableDefault(tb, b, "region", "eu") }, want: []string{"has no variable region"}},
+		{name: "tags", fn: func(tb testing.TB) { grabanatest.AssertTags(tb, b, "team", "owner:sre") }},
+		{name: "tag missing", fn: func(tb testing.TB) { grabanatest.AssertTags(tb, b, "team", "tier:1") }, want: []string{"has no tag tier:1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertFailures(t, record(t, tt.fn), tt.want...)
+		})
+	}
+}
+
+func TestAssertGolden(t *testing.T) {
+	dir := t.TempDir()
+	boards := []dashboard.Builder{assertBoard(t)}
+	assertFailures(t, record(t, func(tb testing.TB) { grabanatest.AssertGolden(tb, dir, boards) }), "golden file "+filepath.Join(dir, "assert.golden.json")+" missing")
+
+	golden, err := grabanatest.Normalize(boards[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "assert.golden.json")
+	if err := os.WriteFile(filename, golden, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// a rebuild changes the panel ids, which Normalize leaves out
+	rebuilt := []dashboard.Builder{assertBoard(t)}
+	assertFailures(t, record(t, func(tb testing.TB) { grabanatest.AssertGolden(tb, dir, rebuilt) }))
+
+	changed := strings.Replace(string(golden), `"title": "Assert"`, `"title": "Old"`, 1)
+	if err := os.WriteFile(filename, []byte(changed), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	assertFailures(t, record(t, func(tb testing.TB) { grabanatest.AssertGolden(tb, dir, boards) }), `assert differs from `+filename)
+}
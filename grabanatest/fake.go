@@ -0,0 +1,94 @@
+// Package grabanatest helps to unit test Creator and DashboardCreator implementations with go test
+package grabanatest
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/K-Phoen/grabana/dashboard"
+	grabanaclistarter "github.com/fasibio/grabana_cli_starter"
+	"github.com/urfave/cli/v2"
+)
+
+// Flags are the flag values of a fake cli.Context by flag name. Supported are string, []string, bool, int,
+// float64 and time.Duration
+type Flags map[string]any
+
+// Context returns a cli.Context with the flags set, the Environment is readable with
+// grabanaclistarter.GetEnvironment
+func Context(t testing.TB, flags Flags, env map[string]string) *cli.Context {
+	t.Helper()
+	set := flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+	for name, value := range flags {
+		var err error
+		switch v := value.(type) {
+		case string:
+			set.String(name, "", "")
+			err = set.Set(name, v)
+		case []string:
+			set.Var(cli.NewStringSlice(), name, "")
+			for _, item := range v {
+				err = set.Set(name, item)
+			}
+		case bool:
+			set.Bool(name, false, "")
+			err = set.Set(name, strconv.FormatBool(v))
+		case int:
+			set.Int(name, 0, "")
+			err = set.Set(name, strconv.Itoa(v))
+		case float64:
+			set.Float64(name, 0, "")
+			err = set.Set(name, strconv.FormatFloat(v, 'f', -1, 64))
+		case time.Duration:
+			set.Duration(name, 0, "")
+			err = set.Set(name, v.String())
+		default:
+			err = fmt.Errorf("unsupported type %T", value)
+		}
+		if err != nil {
+			t.Fatalf("grabanatest: flag %s: %v", name, err)
+		}
+	}
+	environment, err := grabanaclistarter.NewEnvironment(nil, env)
+	if err != nil {
+		t.Fatalf("grabanatest: environment: %v", err)
+	}
+	c := cli.NewContext(cli.NewApp(), set, nil)
+	c.Context = grabanaclistarter.ContextWithEnvironment(context.Background(), environment)
+	return c
+}
+
+// Input returns a CreatorInput with a fake cli.Context as Flags
+func Input(t testing.TB, folderName string, flags Flags, env map[string]string) grabanaclistarter.CreatorInput {
+	t.Helper()
+	c := Context(t, flags, env)
+	return grabanaclistarter.CreatorInput{
+		FolderName:  folderName,
+		Environment: grabanaclistarter.GetEnvironment(c),
+		Flags:       c,
+	}
+}
+
+// Build runs the Creator and fails the test on error
+func Build(t testing.TB, creator grabanaclistarter.Creator, in grabanaclistarter.CreatorInput) []dashboard.Builder {
+	t.Helper()
+	boards, err := creator(in)
+	if err != nil {
+		t.Fatalf("grabanatest: creator failed: %v", err)
+	}
+	return boards
+}
+
+// BuildCli runs the DashboardCreator with a fake cli.Context and fails the test on error
+func BuildCli(t testing.TB, creator grabanaclistarter.DashboardCreator, folderName string, flags Flags, env map[string]string) []dashboard.Builder {
+	t.Helper()
+	boards, err := creator(folderName, Context(t, flags, env))
+	if err != nil {
+		t.Fatalf("grabanatest: creator failed: %v", err)
+	}
+	return boards
+}
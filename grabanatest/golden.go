@@ -0,0 +1,80 @@
+package grabanatest
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/K-Phoen/grabana/dashboard"
+	grabanaclistarter "github.com/fasibio/grabana_cli_starter"
+)
+
+// update is prefixed, so it does not collide with an -update flag of the tested package
+var update = flag.Bool("grabanatest.update", false, "update the golden files of grabanatest")
+
+// Normalize returns the dashboard json with sorted keys and without the ids, which depend on the build order.
+// It uses the same normalization as the status command
+func Normalize(b dashboard.Builder) ([]byte, error) {
+	model, err := grabanaclistarter.NormalizedModel(b)
+	if err != nil {
+		return nil, err
+	}
+	res, err := json.MarshalIndent(model, "", "  ")
+	return append(res, '\n'), err
+}
+
+// AssertGolden compares the normalized json of every dashboard with dir/<uid>.golden.json.
+// Run go test with -grabanatest.update to write the golden files
+func AssertGolden(t testing.TB, dir string, boards []dashboard.Builder) {
+	t.Helper()
+	for _, b := range boards {
+		uid := b.Internal().UID
+		got, err := Normalize(b)
+		if err != nil {
+			t.Fatalf("grabanatest: %s: %v", uid, err)
+		}
+		filename := filepath.Join(dir, uid+".golden.json")
+		if *update {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				t.Fatalf("grabanatest: %v", err)
+			}
+			if err := os.WriteFile(filename, got, 0o644); err != nil {
+				t.Fatalf("grabanatest: %v", err)
+			}
+			continue
+		}
+		want, err := os.ReadFile(filename)
+		if errors.Is(err, os.ErrNotExist) {
+			t.Errorf("grabanatest: golden file %s missing, run go test with -grabanatest.update", filename)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("grabanatest: %v", err)
+		}
+		if line, gotLine, wantLine, diff := firstDiff(string(got), string(want)); diff {
+			t.Errorf("grabanatest: %s differs from %s at line %d:\n got: %s\nwant: %s\nrun go test with -grabanatest.update if the change is intended",
+				uid, filename, line, gotLine, wantLine)
+		}
+	}
+}
+
+func firstDiff(got, want string) (int, string, string, bool) {
+	gotLines, wantLines := strings.Split(got, "\n"), strings.Split(want, "\n")
+	for i := 0; i < max(len(gotLines), len(wantLines)); i++ {
+		g, w := "", ""
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if g != w {
+			return i + 1, strings.TrimSpace(g), strings.TrimSpace(w), true
+		}
+	}
+	return 0, "", "", false
+}
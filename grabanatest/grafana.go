@@ -0,0 +1,664 @@
+package grabanatest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/K-Phoen/grabana"
+	grabanaclistarter "github.com/fasibio/grabana_cli_starter"
+)
+
+// Request is one request received by the fake Grafana
+type Request struct {
+	Method string
+	Path   string
+	Query  string
+	Header http.Header
+	Body   []byte
+}
+
+// Fault makes the fake Grafana answer matching requests with Status after Delay. Status 0 only delays
+type Fault struct {
+	// Method to match, empty matches all
+	Method string
+	// PathPrefix to match, empty matches all
+	PathPrefix string
+	Status     int
+	Delay      time.Duration
+	// Times is the number of requests the fault applies to, 0 means until ClearFaults
+	Times int
+}
+
+func (f *Fault) matches(r *http.Request) bool {
+	return (f.Method == "" || f.Method == r.Method) && strings.HasPrefix(r.URL.Path, f.PathPrefix)
+}
+
+// StoredDashboard is a dashboard saved in the fake Grafana
+type StoredDashboard struct {
+	Model     map[string]any
+	FolderID  uint
+	Version   int
+	UpdatedBy string
+	Updated   time.Time
+}
+
+type datasource struct {
+	ID   int    `json:"id"`
+	UID  string `json:"uid"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// MainOrgID is the organisation used by requests without X-Grafana-Org-Id
+const MainOrgID = 1
+
+// orgStore holds the folders and dashboards of one organisation
+type orgStore struct {
+	folders    []grabana.Folder
+	dashboards map[string]*StoredDashboard
+}
+
+// Grafana is an in process fake of the grafana http api used by grabana and the cli: health, user, orgs,
+// folders, search, dashboards, datasources, api keys and the ruler/alertmanager endpoints of the alerts.
+// Folders and dashboards are stored per X-Grafana-Org-Id, datasources, api keys and alert rules are shared
+// by all organisations
+type Grafana struct {
+	*httptest.Server
+	// Version is reported by /api/health
+	Version string
+	// User is reported by /api/user and stored as updatedBy of dashboards
+	User string
+
+	mu          sync.Mutex
+	nextID      int
+	stores      map[int]*orgStore
+	datasources []*datasource
+	apiKeys     []grabana.APIKey
+	rules       map[string]map[string]json.RawMessage
+	orgs        map[string]int
+	requests    []Request
+	faults      []*Fault
+}
+
+// NewGrafana starts a fake Grafana which is closed at the end of the test
+func NewGrafana(t testing.TB) *Grafana {
+	g := &Grafana{
+		Version: "10.4.1",
+		User:    "admin",
+		stores:  map[int]*orgStore{},
+		rules:   map[string]map[string]json.RawMessage{},
+		orgs:    map[string]int{"Main Org.": 1},
+	}
+	g.Server = httptest.NewServer(http.HandlerFunc(g.serveHTTP))
+	t.Cleanup(g.Close)
+	return g
+}
+
+// Client returns a grabana client for the fake
+func (g *Grafana) Client() *grabana.Client {
+	return grabana.NewClient(g.Server.Client(), g.URL)
+}
+
+// Runner returns a Runner working against the fake
+func (g *Grafana) Runner(creator grabanaclistarter.Creator) *grabanaclistarter.Runner {
+	r := grabanaclistarter.NewRunner(g.URL, g.Client(), creator)
+	r.HTTPClient = g.Server.Client()
+	return r
+}
+
+// InjectFault adds a fault, faults are checked in the order they were added
+func (g *Grafana) InjectFault(f Fault) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.faults = append(g.faults, &f)
+}
+
+func (g *Grafana) ClearFaults() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.faults = nil
+}
+
+// Requests returns all received requests
+func (g *Grafana) Requests() []Request {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]Request{}, g.requests...)
+}
+
+// RequestsTo returns the received requests with method and path
+func (g *Grafana) RequestsTo(method, path string) []Request {
+	res := []Request{}
+	for _, r := range g.Requests() {
+		if r.Method == method && r.Path == path {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+func (g *Grafana) store(orgID int) *orgStore {
+	o, ok := g.stores[orgID]
+	if !ok {
+		o = &orgStore{dashboards: map[string]*StoredDashboard{}}
+		g.stores[orgID] = o
+	}
+	return o
+}
+
+// requestStore returns the store of the organisation selected by X-Grafana-Org-Id
+func (g *Grafana) requestStore(r *http.Request) *orgStore {
+	orgID, err := strconv.Atoi(r.Header.Get("X-Grafana-Org-Id"))
+	if err != nil {
+		orgID = MainOrgID
+	}
+	return g.store(orgID)
+}
+
+// Dashboard returns a copy of the stored dashboard of the main organisation
+func (g *Grafana) Dashboard(uid string) (StoredDashboard, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	d, ok := g.store(MainOrgID).dashboards[uid]
+	if !ok {
+		return StoredDashboard{}, false
+	}
+	return *d, true
+}
+
+// DashboardUIDs returns the uids of all stored dashboards of the main organisation sorted
+func (g *Grafana) DashboardUIDs() []string {
+	return g.OrgDashboardUIDs(MainOrgID)
+}
+
+// OrgDashboardUIDs returns the uids of all stored dashboards of the organisation sorted
+func (g *Grafana) OrgDashboardUIDs(orgID int) []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return sortedKeys(g.store(orgID).dashboards)
+}
+
+// Folders returns all folders of the main organisation
+func (g *Grafana) Folders() []grabana.Folder {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]grabana.Folder{}, g.store(MainOrgID).folders...)
+}
+
+// AddFolder creates a folder in the main organisation like a user would in the ui
+func (g *Grafana) AddFolder(title string) grabana.Folder {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.addFolder(g.store(MainOrgID), title)
+}
+
+// AddDashboard stores a dashboard in the main organisation like a user would in the ui, model needs a uid
+func (g *Grafana) AddDashboard(model map[string]any, folderID uint, updatedBy string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.saveDashboard(g.store(MainOrgID), model, folderID, updatedBy)
+}
+
+// AddDatasource creates a datasource
+func (g *Grafana) AddDatasource(name, typ string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.nextID++
+	g.datasources = append(g.datasources, &datasource{ID: g.nextID, UID: fmt.Sprintf("ds-%d", g.nextID), Name: name, Type: typ})
+}
+
+func (g *Grafana) addFolder(o *orgStore, title string) grabana.Folder {
+	g.nextID++
+	f := grabana.Folder{ID: uint(g.nextID), UID: fmt.Sprintf("folder-%d", g.nextID), Title: title}
+	o.folders = append(o.folders, f)
+	return f
+}
+
+var slugInvalid = regexp.MustCompile(`[^a-z0-9]+`)
+
+func slug(title string) string {
+	return strings.Trim(slugInvalid.ReplaceAllString(strings.ToLower(title), "-"), "-")
+}
+
+func (g *Grafana) saveDashboard(o *orgStore, model map[string]any, folderID uint, updatedBy string) *StoredDashboard {
+	uid, _ := model["uid"].(string)
+	d, ok := o.dashboards[uid]
+	if !ok {
+		g.nextID++
+		d = &StoredDashboard{}
+		o.dashboards[uid] = d
+		model["id"] = g.nextID
+	} else {
+		model["id"] = d.Model["id"]
+	}
+	d.Version++
+	model["version"] = d.Version
+	d.Model, d.FolderID, d.UpdatedBy, d.Updated = model, folderID, updatedBy, time.Now()
+	return d
+}
+
+func (o *orgStore) folder(id uint) grabana.Folder {
+	for _, f := range o.folders {
+		if f.ID == id {
+			return f
+		}
+	}
+	return grabana.Folder{Title: "General"}
+}
+
+func (g *Grafana) dashboardURL(d *StoredDashboard) string {
+	uid, _ := d.Model["uid"].(string)
+	title, _ := d.Model["title"].(string)
+	return "/d/" + uid + "/" + slug(title)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func message(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"message": msg})
+}
+
+// fault returns the first matching fault and counts it down
+func (g *Grafana) fault(r *http.Request) *Fault {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for i, f := range g.faults {
+		if !f.matches(r) {
+			continue
+		}
+		res := *f
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				g.faults = append(g.faults[:i], g.faults[i+1:]...)
+			}
+		}
+		return &res
+	}
+	return nil
+}
+
+func (g *Grafana) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	g.mu.Lock()
+	g.requests = append(g.requests, Request{Method: r.Method, Path: r.URL.Path, Query: r.URL.RawQuery, Header: r.Header.Clone(), Body: body})
+	g.mu.Unlock()
+
+	if f := g.fault(r); f != nil {
+		select {
+		case <-time.After(f.Delay):
+		case <-r.Context().Done():
+			return
+		}
+		if f.Status != 0 {
+			if f.Status == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "1")
+			}
+			message(w, f.Status, http.StatusText(f.Status))
+			return
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	switch {
+	case path == "/api/health":
+		writeJSON(w, http.StatusOK, map[string]string{"database": "ok", "version": g.Version})
+	case path == "/api/user":
+		writeJSON(w, http.StatusOK, map[string]any{"id": 1, "login": g.User})
+	case path == "/api/orgs" || strings.HasPrefix(path, "/api/orgs/"):
+		g.serveOrgs(w, r, path)
+	case path == "/api/folders":
+		g.serveFolders(w, r, g.requestStore(r), body)
+	case path == "/api/search":
+		g.serveSearch(w, r, g.requestStore(r))
+	case path == "/api/dashboards/db" && r.Method == http.MethodPost:
+		g.serveSaveDashboard(w, g.requestStore(r), body)
+	case strings.HasPrefix(path, "/api/dashboards/uid/"):
+		g.serveDashboard(w, r, g.requestStore(r), strings.TrimPrefix(path, "/api/dashboards/uid/"))
+	case path == "/api/datasources" || strings.HasPrefix(path, "/api/datasources/"):
+		g.serveDatasources(w, r, path, body)
+	case path == "/api/auth/keys" || strings.HasPrefix(path, "/api/auth/keys/"):
+		g.serveAPIKeys(w, r, path, body)
+	case strings.HasPrefix(path, "/api/ruler/grafana/api/v1/rules"):
+		g.serveRules(w, r, strings.TrimPrefix(strings.TrimPrefix(path, "/api/ruler/grafana/api/v1/rules"), "/"), body)
+	case path == "/api/alertmanager/grafana/config/api/v1/alerts" && r.Method == http.MethodPost:
+		message(w, http.StatusAccepted, "configuration created")
+	default:
+		message(w, http.StatusNotFound, "Not found")
+	}
+}
+
+func (g *Grafana) serveOrgs(w http.ResponseWriter, r *http.Request, path string) {
+	switch {
+	case path == "/api/orgs" && r.Method == http.MethodPost:
+		req := struct {
+			Name string `json:"name"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			message(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if _, ok := g.orgs[req.Name]; ok {
+			message(w, http.StatusConflict, "Organization name taken")
+			return
+		}
+		g.orgs[req.Name] = len(g.orgs) + 1
+		writeJSON(w, http.StatusOK, map[string]any{"orgId": g.orgs[req.Name], "message": "Organization created"})
+	case strings.HasPrefix(path, "/api/orgs/name/") && r.Method == http.MethodGet:
+		id, ok := g.orgs[strings.TrimPrefix(path, "/api/orgs/name/")]
+		if !ok {
+			message(w, http.StatusNotFound, "Organization not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"id": id, "name": strings.TrimPrefix(path, "/api/orgs/name/")})
+	default:
+		message(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (g *Grafana) serveFolders(w http.ResponseWriter, r *http.Request, o *orgStore, body []byte) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, o.folders)
+	case http.MethodPost:
+		req := struct {
+			Title string `json:"title"`
+		}{}
+		if err := json.Unmarshal(body, &req); err != nil || req.Title == "" {
+			message(w, http.StatusBadRequest, "folder title missing")
+			return
+		}
+		for _, f := range o.folders {
+			if strings.EqualFold(f.Title, req.Title) {
+				message(w, http.StatusConflict, "a folder with the same name already exists")
+				return
+			}
+		}
+		writeJSON(w, http.StatusOK, g.addFolder(o, req.Title))
+	default:
+		message(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (g *Grafana) searchEntry(o *orgStore, d *StoredDashboard) grabana.Dashboard {
+	f := o.folder(d.FolderID)
+	entry := grabana.Dashboard{
+		URL:         g.dashboardURL(d),
+		FolderID:    int(f.ID),
+		FolderUID:   f.UID,
+		FolderTitle: f.Title,
+		Tags:        []string{},
+	}
+	entry.ID, _ = d.Model["id"].(int)
+	entry.UID, _ = d.Model["uid"].(string)
+	entry.Title, _ = d.Model["title"].(string)
+	if tags, ok := d.Model["tags"].([]any); ok {
+		for _, t := range tags {
+			entry.Tags = append(entry.Tags, fmt.Sprint(t))
+		}
+	}
+	return entry
+}
+
+func (g *Grafana) serveSearch(w http.ResponseWriter, r *http.Request, o *orgStore) {
+	query := strings.ToLower(r.URL.Query().Get("query"))
+	res := []grabana.Dashboard{}
+	for _, uid := range sortedKeys(o.dashboards) {
+		entry := g.searchEntry(o, o.dashboards[uid])
+		if strings.Contains(strings.ToLower(entry.Title), query) {
+			res = append(res, entry)
+		}
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func (g *Grafana) serveSaveDashboard(w http.ResponseWriter, o *orgStore, body []byte) {
+	req := struct {
+		Dashboard map[string]any `json:"dashboard"`
+		FolderID  uint           `json:"folderId"`
+		Overwrite bool           `json:"overwrite"`
+	}{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		message(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	uid, _ := req.Dashboard["uid"].(string)
+	if uid == "" {
+		message(w, http.StatusBadRequest, "Dashboard uid missing")
+		return
+	}
+	if _, exists := o.dashboards[uid]; exists && !req.Overwrite {
+		writeJSON(w, http.StatusPreconditionFailed, map[string]string{"status": "name-exists", "message": "A dashboard with the same uid already exists"})
+		return
+	}
+	d := g.saveDashboard(o, req.Dashboard, req.FolderID, g.User)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id":      d.Model["id"],
+		"uid":     uid,
+		"url":     g.dashboardURL(d),
+		"status":  "success",
+		"version": d.Version,
+		"slug":    slug(fmt.Sprint(d.Model["title"])),
+	})
+}
+
+func (g *Grafana) serveDashboard(w http.ResponseWriter, r *http.Request, o *orgStore, uid string) {
+	d, ok := o.dashboards[uid]
+	if !ok {
+		message(w, http.StatusNotFound, "Dashboard not found")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		f := o.folder(d.FolderID)
+		writeJSON(w, http.StatusOK, map[string]any{
+			"dashboard": d.Model,
+			"meta": map[string]any{
+				"url":         g.dashboardURL(d),
+				"folderId":    f.ID,
+				"folderUid":   f.UID,
+				"folderTitle": f.Title,
+				"updated":     d.Updated.Format(time.RFC3339),
+				"updatedBy":   d.UpdatedBy,
+				"version":     d.Version,
+			},
+		})
+	case http.MethodDelete:
+		delete(o.dashboards, uid)
+		writeJSON(w, http.StatusOK, map[string]any{"title": d.Model["title"], "message": fmt.Sprintf("Dashboard %v deleted", d.Model["title"])})
+	default:
+		message(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (g *Grafana) datasourceBy(match func(*datasource) bool) (int, *datasource) {
+	for i, ds := range g.datasources {
+		if match(ds) {
+			return i, ds
+		}
+	}
+	return -1, nil
+}
+
+func (g *Grafana) serveDatasources(w http.ResponseWriter, r *http.Request, path string, body []byte) {
+	rest := strings.TrimPrefix(strings.TrimPrefix(path, "/api/datasources"), "/")
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, g.datasources)
+	case rest == "" && r.Method == http.MethodPost:
+		ds := &datasource{}
+		if err := json.Unmarshal(body, ds); err != nil || ds.Name == "" {
+			message(w, http.StatusBadRequest, "datasource name missing")
+			return
+		}
+		if _, existing := g.datasourceBy(func(d *datasource) bool { return d.Name == ds.Name }); existing != nil {
+			message(w, http.StatusConflict, "data source with the same name already exists")
+			return
+		}
+		g.nextID++
+		ds.ID = g.nextID
+		if ds.UID == "" {
+			ds.UID = fmt.Sprintf("ds-%d", ds.ID)
+		}
+		g.datasources = append(g.datasources, ds)
+		writeJSON(w, http.StatusOK, map[string]any{"id": ds.ID, "name": ds.Name, "message": "Datasource added"})
+	case strings.HasPrefix(rest, "name/"), strings.HasPrefix(rest, "id/"):
+		name := rest[strings.Index(rest, "/")+1:]
+		_, ds := g.datasourceBy(func(d *datasource) bool { return d.Name == name })
+		if ds == nil {
+			message(w, http.StatusNotFound, "Data source not found")
+			return
+		}
+		if strings.HasPrefix(rest, "id/") {
+			writeJSON(w, http.StatusOK, map[string]int{"id": ds.ID})
+			return
+		}
+		writeJSON(w, http.StatusOK, ds)
+	default:
+		id, err := strconv.Atoi(rest)
+		i, ds := g.datasourceBy(func(d *datasource) bool { return d.ID == id })
+		if err != nil || ds == nil {
+			message(w, http.StatusNotFound, "Data source not found")
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, ds)
+		case http.MethodPut:
+			updated := &datasource{}
+			if err := json.Unmarshal(body, updated); err != nil {
+				message(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			updated.ID = ds.ID
+			if updated.UID == "" {
+				updated.UID = ds.UID
+			}
+			g.datasources[i] = updated
+			writeJSON(w, http.StatusOK, map[string]any{"id": ds.ID, "message": "Datasource updated"})
+		case http.MethodDelete:
+			g.datasources = append(g.datasources[:i], g.datasources[i+1:]...)
+			message(w, http.StatusOK, "Data source deleted")
+		default:
+			message(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+func (g *Grafana) serveAPIKeys(w http.ResponseWriter, r *http.Request, path string, body []byte) {
+	rest := strings.TrimPrefix(strings.TrimPrefix(path, "/api/auth/keys"), "/")
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, g.apiKeys)
+	case rest == "" && r.Method == http.MethodPost:
+		req := grabana.CreateAPIKeyRequest{}
+		if err := json.Unmarshal(body, &req); err != nil || req.Name == "" {
+			message(w, http.StatusBadRequest, "api key name missing")
+			return
+		}
+		for _, k := range g.apiKeys {
+			if k.Name == req.Name {
+				message(w, http.StatusConflict, "API Key Organization ID And Name Must Be Unique")
+				return
+			}
+		}
+		g.nextID++
+		g.apiKeys = append(g.apiKeys, grabana.APIKey{ID: uint(g.nextID), Name: req.Name})
+		writeJSON(w, http.StatusOK, map[string]any{"id": g.nextID, "name": req.Name, "key": fmt.Sprintf("fake-key-%d", g.nextID)})
+	case r.Method == http.MethodDelete:
+		id, _ := strconv.Atoi(rest)
+		for i, k := range g.apiKeys {
+			if int(k.ID) == id {
+				g.apiKeys = append(g.apiKeys[:i], g.apiKeys[i+1:]...)
+				message(w, http.StatusOK, "API key deleted")
+				return
+			}
+		}
+		message(w, http.StatusNotFound, "API key not found")
+	default:
+		message(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// ruleGroupDashboard returns the dashboard uid the alert rules of the group are linked to
+func ruleGroupDashboard(group json.RawMessage) string {
+	g := struct {
+		Rules []struct {
+			Annotations map[string]string `json:"annotations"`
+		} `json:"rules"`
+	}{}
+	_ = json.Unmarshal(group, &g)
+	for _, rule := range g.Rules {
+		if uid := rule.Annotations["__dashboardUid__"]; uid != "" {
+			return uid
+		}
+	}
+	return ""
+}
+
+func (g *Grafana) serveRules(w http.ResponseWriter, r *http.Request, rest string, body []byte) {
+	parts := strings.SplitN(rest, "/", 2)
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		dashboardUID := r.URL.Query().Get("dashboard_uid")
+		res := map[string][]json.RawMessage{}
+		for _, namespace := range sortedKeys(g.rules) {
+			for _, name := range sortedKeys(g.rules[namespace]) {
+				group := g.rules[namespace][name]
+				if dashboardUID == "" || ruleGroupDashboard(group) == dashboardUID {
+					res[namespace] = append(res[namespace], group)
+				}
+			}
+		}
+		writeJSON(w, http.StatusOK, res)
+	case len(parts) == 1 && r.Method == http.MethodPost:
+		group := struct {
+			Name string `json:"name"`
+		}{}
+		if err := json.Unmarshal(body, &group); err != nil || group.Name == "" {
+			message(w, http.StatusBadRequest, "rule group name missing")
+			return
+		}
+		if g.rules[parts[0]] == nil {
+			g.rules[parts[0]] = map[string]json.RawMessage{}
+		}
+		g.rules[parts[0]][group.Name] = json.RawMessage(body)
+		message(w, http.StatusAccepted, "rule group updated successfully")
+	case len(parts) == 2 && r.Method == http.MethodDelete:
+		if _, ok := g.rules[parts[0]][parts[1]]; !ok {
+			message(w, http.StatusNotFound, "rule group not found")
+			return
+		}
+		delete(g.rules[parts[0]], parts[1])
+		message(w, http.StatusAccepted, "rule group deleted")
+	default:
+		message(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
@@ -0,0 +1,307 @@
+package grabanatest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/K-Phoen/grabana/dashboard"
+	"github.com/K-Phoen/grabana/row"
+	grabanaclistarter "github.com/fasibio/grabana_cli_starter"
+	"github.com/fasibio/grabana_cli_starter/grabanatest"
+)
+
+var inFolder = grabanaclistarter.CreatorInput{FolderName: "f"}
+
+// boards returns a Creator building one empty dashboard per uid
+func boards(uids ...string) grabanaclistarter.Creator {
+	return func(in grabanaclistarter.CreatorInput) ([]dashboard.Builder, error) {
+		res := []dashboard.Builder{}
+		for _, uid := range uids {
+			b, err := dashboard.New("Board "+uid, dashboard.UID(uid))
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, b)
+		}
+		return res, nil
+	}
+}
+
+func statuses(results []grabanaclistarter.DashboardResult) map[string]grabanaclistarter.ResultStatus {
+	res := map[string]grabanaclistarter.ResultStatus{}
+	for _, r := range results {
+		res[r.UID] = r.Status
+	}
+	return res
+}
+
+func assertStatuses(t *testing.T, results []grabanaclistarter.DashboardResult, want map[string]grabanaclistarter.ResultStatus) {
+	t.Helper()
+	got := statuses(results)
+	if len(got) != len(want) {
+		t.Errorf("got %d results %v, want %v", len(got), got, want)
+	}
+	for uid, status := range want {
+		if got[uid] != status {
+			t.Errorf("dashboard %s has status %q, want %q", uid, got[uid], status)
+		}
+	}
+}
+
+// cancelDuringRequest cancels the context while the first delayed request of method to path is in flight
+func cancelDuringRequest(t *testing.T, g *grabanatest.Grafana, method, path string) context.Context {
+	t.Helper()
+	g.InjectFault(grabanatest.Fault{Method: method, PathPrefix: path, Delay: 200 * time.Millisecond, Times: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	time.AfterFunc(50*time.Millisecond, cancel)
+	return ctx
+}
+
+func TestApplyStopsBetweenDashboards(t *testing.T) {
+	g := grabanatest.NewGrafana(t)
+	r := g.Runner(boards("a", "b"))
+	ctx := cancelDuringRequest(t, g, http.MethodPost, "/api/dashboards/db")
+	results, err := r.Apply(ctx, grabanaclistarter.ApplyOptions{CreatorInput: inFolder})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want context.Canceled", err)
+	}
+	assertStatuses(t, results, map[string]grabanaclistarter.ResultStatus{"a": grabanaclistarter.StatusDone, "b": grabanaclistarter.StatusSkipped})
+	if uids := g.DashboardUIDs(); len(uids) != 1 || uids[0] != "a" {
+		t.Errorf("grafana has dashboards %v, want [a]", uids)
+	}
+}
+
+func TestDestroyStopsBetweenDashboards(t *testing.T) {
+	g := grabanatest.NewGrafana(t)
+	r := g.Runner(boards("a", "b"))
+	if _, err := r.Apply(context.Background(), grabanaclistarter.ApplyOptions{CreatorInput: inFolder}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := cancelDuringRequest(t, g, http.MethodDelete, "/api/dashboards/uid/")
+	results, err := r.Destroy(ctx, grabanaclistarter.DestroyOptions{CreatorInput: inFolder})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want context.Canceled", err)
+	}
+	assertStatuses(t, results, map[string]grabanaclistarter.ResultStatus{"a": grabanaclistarter.StatusDone, "b": grabanaclistarter.StatusSkipped})
+	if uids := g.DashboardUIDs(); len(uids) != 1 || uids[0] != "b" {
+		t.Errorf("grafana has dashboards %v, want [b]", uids)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	g := grabanatest.NewGrafana(t)
+	r := g.Runner(boards("a"))
+	r.RequestTimeout = 50 * time.Millisecond
+	g.InjectFault(grabanatest.Fault{Method: http.MethodPost, PathPrefix: "/api/dashboards/db", Delay: time.Second})
+	results, err := r.Apply(context.Background(), grabanaclistarter.ApplyOptions{CreatorInput: inFolder})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want context.DeadlineExceeded", err)
+	}
+	assertStatuses(t, results, map[string]grabanaclistarter.ResultStatus{"a": grabanaclistarter.StatusFailed})
+}
+
+func TestDestroyCliSkipsMissingDashboards(t *testing.T) {
+	g := grabanatest.NewGrafana(t)
+	if _, err := g.Runner(boards("a", "c")).Apply(context.Background(), grabanaclistarter.ApplyOptions{CreatorInput: inFolder}); err != nil {
+		t.Fatal(err)
+	}
+	app, err := grabanaclistarter.NewCli("destroytest", grabanaclistarter.WithCreator(boards("a", "b", "c")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the lookup of c fails, it is removed anyway
+	g.InjectFault(grabanatest.Fault{Method: http.MethodGet, PathPrefix: "/api/dashboards/uid/c", Status: http.StatusInternalServerError, Times: 1})
+	t.Setenv(grabanaclistarter.GetFlagEnvByFlagName(grabanaclistarter.CliYes, "destroytest"), "true")
+	err = app.Run([]string{"destroytest", "--config", "", "dashboard", "--server", g.URL, "--apikey", "token", "--foldername", "f", "destroy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uids := g.DashboardUIDs(); len(uids) != 0 {
+		t.Errorf("grafana still has dashboards %v", uids)
+	}
+	if deletes := g.RequestsTo(http.MethodDelete, "/api/dashboards/uid/b"); len(deletes) != 0 {
+		t.Errorf("missing dashboard b was deleted %d times", len(deletes))
+	}
+}
+
+func syncStates(status []grabanaclistarter.DashboardStatus) map[string]grabanaclistarter.SyncState {
+	res := map[string]grabanaclistarter.SyncState{}
+	for _, s := range status {
+		res[s.UID] = s.State
+	}
+	return res
+}
+
+func TestApplyStatusDestroy(t *testing.T) {
+	g := grabanatest.NewGrafana(t)
+	r := g.Runner(boards("a", "b"))
+	ctx := context.Background()
+
+	status, err := r.Status(ctx, grabanaclistarter.StatusOptions{CreatorInput: inFolder})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := syncStates(status); got["a"] != grabanaclistarter.StateMissing || got["b"] != grabanaclistarter.StateMissing {
+		t.Errorf("got states %v before apply, want missing", got)
+	}
+
+	// a slow grafana delays the apply without failing it
+	g.InjectFault(grabanatest.Fault{Method: http.MethodPost, PathPrefix: "/api/dashboards/db", Delay: 50 * time.Millisecond, Times: 1})
+	results, err := r.Apply(ctx, grabanaclistarter.ApplyOptions{CreatorInput: inFolder})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatuses(t, results, map[string]grabanaclistarter.ResultStatus{"a": grabanaclistarter.StatusDone, "b": grabanaclistarter.StatusDone})
+	if results[0].Version != 1 || results[0].URL != g.URL+"/d/a/board-a" {
+		t.Errorf("got version %d and url %s", results[0].Version, results[0].URL)
+	}
+
+	live, _ := g.Dashboard("b")
+	model := map[string]any{}
+	for k, v := range live.Model {
+		model[k] = v
+	}
+	model["title"] = "Changed in ui"
+	g.AddDashboard(model, live.FolderID, "alice")
+	status, err = r.Status(ctx, grabanaclistarter.StatusOptions{CreatorInput: inFolder})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]grabanaclistarter.SyncState{"a": grabanaclistarter.StateInSync, "b": grabanaclistarter.StateModified}
+	if got := syncStates(status); !reflect.DeepEqual(got, want) {
+		t.Errorf("got states %v after apply, want %v", got, want)
+	}
+
+	results, err = r.Destroy(ctx, grabanaclistarter.DestroyOptions{CreatorInput: inFolder})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatuses(t, results, map[string]grabanaclistarter.ResultStatus{"a": grabanaclistarter.StatusDone, "b": grabanaclistarter.StatusDone})
+	if uids := g.DashboardUIDs(); len(uids) != 0 {
+		t.Errorf("grafana still has dashboards %v", uids)
+	}
+}
+
+func TestGrafanaFaults(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+	}{
+		{name: "rate limited", status: http.StatusTooManyRequests},
+		{name: "server error", status: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := grabanatest.NewGrafana(t)
+			r := g.Runner(boards("a", "b"))
+			ctx := context.Background()
+
+			g.InjectFault(grabanatest.Fault{Method: http.MethodPost, PathPrefix: "/api/dashboards/db", Status: tt.status, Times: 1})
+			results, err := r.Apply(ctx, grabanaclistarter.ApplyOptions{CreatorInput: inFolder})
+			if err == nil {
+				t.Error("apply: expected an error")
+			}
+			assertStatuses(t, results, map[string]grabanaclistarter.ResultStatus{"a": grabanaclistarter.StatusFailed, "b": grabanaclistarter.StatusDone})
+			if uids := g.DashboardUIDs(); !reflect.DeepEqual(uids, []string{"b"}) {
+				t.Errorf("grafana has dashboards %v, want [b]", uids)
+			}
+
+			g.InjectFault(grabanatest.Fault{Method: http.MethodGet, PathPrefix: "/api/dashboards/uid/b", Status: tt.status, Times: 1})
+			status, err := r.Status(ctx, grabanaclistarter.StatusOptions{CreatorInput: inFolder})
+			if err == nil {
+				t.Error("status: expected an error")
+			}
+			want := map[string]grabanaclistarter.SyncState{"a": grabanaclistarter.StateMissing, "b": grabanaclistarter.StateError}
+			if got := syncStates(status); !reflect.DeepEqual(got, want) {
+				t.Errorf("got states %v, want %v", got, want)
+			}
+
+			g.InjectFault(grabanatest.Fault{Method: http.MethodDelete, PathPrefix: "/api/dashboards/uid/b", Status: tt.status, Times: 1})
+			results, err = r.Destroy(ctx, grabanaclistarter.DestroyOptions{CreatorInput: inFolder})
+			if err == nil {
+				t.Error("destroy: expected an error")
+			}
+			assertStatuses(t, results, map[string]grabanaclistarter.ResultStatus{"a": grabanaclistarter.StatusMissing, "b": grabanaclistarter.StatusFailed})
+			if uids := g.DashboardUIDs(); !reflect.DeepEqual(uids, []string{"b"}) {
+				t.Errorf("grafana has dashboards %v, want [b]", uids)
+			}
+		})
+	}
+}
+
+func TestApplyInOrg(t *testing.T) {
+	g := grabanatest.NewGrafana(t)
+	r := g.Runner(boards("a"))
+	if err := r.UseOrg(2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Apply(context.Background(), grabanaclistarter.ApplyOptions{CreatorInput: inFolder}); err != nil {
+		t.Fatal(err)
+	}
+	if uids := g.OrgDashboardUIDs(2); !reflect.DeepEqual(uids, []string{"a"}) {
+		t.Errorf("org 2 has dashboards %v, want [a]", uids)
+	}
+	if uids := g.DashboardUIDs(); len(uids) != 0 {
+		t.Errorf("main org has dashboards %v", uids)
+	}
+}
+
+// graphBoard is a Creator building one dashboard with a legacy graph panel
+func graphBoard(in grabanaclistarter.CreatorInput) ([]dashboard.Builder, error) {
+	b, err := dashboard.New("Graph", dashboard.UID("graph"), dashboard.Row("r", row.WithGraph("cpu")))
+	if err != nil {
+		return nil, err
+	}
+	return []dashboard.Builder{b}, nil
+}
+
+func TestApplyCompatibility(t *testing.T) {
+	tests := []struct {
+		version string
+		status  grabanaclistarter.ResultStatus
+	}{
+		{version: "10.4.1", status: grabanaclistarter.StatusDone},
+		{version: "11.0.0", status: grabanaclistarter.StatusBlocked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			g := grabanatest.NewGrafana(t)
+			g.Version = tt.version
+			results, err := g.Runner(graphBoard).Apply(context.Background(), grabanaclistarter.ApplyOptions{CreatorInput: inFolder})
+			if (err != nil) != (tt.status == grabanaclistarter.StatusBlocked) {
+				t.Errorf("got error %v", err)
+			}
+			assertStatuses(t, results, map[string]grabanaclistarter.ResultStatus{"graph": tt.status})
+		})
+	}
+}
+
+func TestPlanStaysOffline(t *testing.T) {
+	g := grabanatest.NewGrafana(t)
+	g.Version = "11.0.0"
+	r := g.Runner(graphBoard)
+	results, err := r.Plan(context.Background(), grabanaclistarter.PlanOptions{CreatorInput: inFolder})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests := g.Requests(); len(requests) != 0 {
+		t.Errorf("plan sent %d requests to grafana", len(requests))
+	}
+	if len(results) != 1 || len(results[0].Violations) != 0 {
+		t.Errorf("got %+v, want no compatibility check without GrafanaVersion", results)
+	}
+
+	r.GrafanaVersion = "11.0.0"
+	results, err = r.Plan(context.Background(), grabanaclistarter.PlanOptions{CreatorInput: inFolder})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || len(results[0].Violations) != 1 || results[0].Violations[0].Severity != grabanaclistarter.SeverityError {
+		t.Errorf("got %+v, want the graph panel reported with an explicit GrafanaVersion", results)
+	}
+}
@@ -0,0 +1,89 @@
+package grabanaclistarter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/K-Phoen/grabana"
+)
+
+// LiveDashboard is a dashboard as stored in grafana
+type LiveDashboard struct {
+	Model map[string]any `json:"dashboard"`
+	Meta  struct {
+		URL         string `json:"url"`
+		FolderUID   string `json:"folderUid"`
+		FolderTitle string `json:"folderTitle"`
+		Updated     string `json:"updated"`
+		UpdatedBy   string `json:"updatedBy"`
+	} `json:"meta"`
+}
+
+func (d LiveDashboard) Version() int {
+	v, _ := d.Model["version"].(float64)
+	return int(v)
+}
+
+// grafanaRequest sends a raw request for the grafana api grabana does not cover. It needs Runner.HTTPClient
+func (r *Runner) grafanaRequest(ctx context.Context, method, path string, body, out any) error {
+	if r.HTTPClient == nil {
+		return fmt.Errorf("Runner.HTTPClient is not set")
+	}
+	return grafanaRequest(ctx, r.HTTPClient, r.Server, method, path, body, out)
+}
+
+func grafanaRequest(ctx context.Context, client *http.Client, server, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(content)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(server, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return grabana.ErrDashboardNotFound
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		content, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("could not query grafana: %s (HTTP status %d)", content, resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// LiveDashboard loads the dashboard by uid from grafana. It returns grabana.ErrDashboardNotFound if missing
+func (r *Runner) LiveDashboard(ctx context.Context, uid string) (*LiveDashboard, error) {
+	var res LiveDashboard
+	err := r.grafanaRequest(ctx, http.MethodGet, "/api/dashboards/uid/"+url.PathEscape(uid), nil, &res)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func isNotFound(err error) bool {
+	return errors.Is(err, grabana.ErrDashboardNotFound)
+}
@@ -0,0 +1,317 @@
+package grabanaclistarter
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fasibio/grabana_cli_starter/recordingrules"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	CliExposition CliValues = "exposition"
+	CliMetricsURL CliValues = "metrics-url"
+)
+
+func inventoryFlags(appName string) []cli.Flag {
+	return append(append(creatorFlags(appName), filterFlags(appName)...),
+		&cli.StringFlag{
+			Name:    CliExposition,
+			EnvVars: []string{GetFlagEnvByFlagName(CliExposition, appName)},
+			Usage:   "prometheus exposition file to compare the inventory with",
+		},
+		&cli.StringFlag{
+			Name:    CliMetricsURL,
+			EnvVars: []string{GetFlagEnvByFlagName(CliMetricsURL, appName)},
+			Usage:   "live /metrics endpoint to compare the inventory with",
+		},
+		&cli.DurationFlag{
+			Name:    CliRequestTimeout,
+			EnvVars: []string{GetFlagEnvByFlagName(CliRequestTimeout, appName)},
+			Value:   30 * time.Second,
+			Usage:   "timeout of the request to the metrics url (0 = none)",
+		},
+	)
+}
+
+// WithRecordingMap adds the rules of the RecodingMaps filled by the Creator to the inventory. The maps log with
+// the Logger of the Runner
+func WithRecordingMap(maps ...*recordingrules.RecodingMap) Option {
+	return func(runner *Runner, app *cli.App) error {
+		for _, m := range maps {
+			m.SetLogger(runner.logger())
+		}
+		runner.RecordingMaps = append(runner.RecordingMaps, maps...)
+		return nil
+	}
+}
+
+// MetricUsage is one metric referenced by the dashboards or recording rules
+type MetricUsage struct {
+	Name string `json:"name" yaml:"name"`
+	// Matchers are the label matchers used with the metric, like job="api". Matchers on grafana variables are left out
+	Matchers []string `json:"matchers" yaml:"matchers"`
+	// Sources are the dashboard panels ("uid / panel") and recording rules ("rule name") using the metric
+	Sources []string `json:"sources" yaml:"sources"`
+	// Recorded is set if the metric is produced by a recording rule
+	Recorded bool `json:"recorded" yaml:"recorded"`
+	// Exposed is set after a compare, if the metric is part of the exposition (nil without compare)
+	Exposed *bool `json:"exposed,omitempty" yaml:"exposed,omitempty"`
+}
+
+// Missing reports metrics neither exposed nor recorded
+func (m MetricUsage) Missing() bool {
+	return m.Exposed != nil && !*m.Exposed && !m.Recorded
+}
+
+type InventoryOptions struct {
+	CreatorInput
+	Filter Filter
+}
+
+type inventory map[string]*MetricUsage
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// add collects all vector selectors of expr
+func (inv inventory) add(expr, source string) error {
+	node, err := parser.ParseExpr(promqlPlaceholders(expr))
+	if err != nil {
+		return fmt.Errorf("Error by %s: %w", source, err)
+	}
+	parser.Inspect(node, func(n parser.Node, _ []parser.Node) error {
+		vs, ok := n.(*parser.VectorSelector)
+		if !ok {
+			return nil
+		}
+		name := vs.Name
+		matchers := []string{}
+		for _, m := range vs.LabelMatchers {
+			if m.Name == labels.MetricName {
+				if name == "" && m.Type == labels.MatchEqual {
+					name = m.Value
+				}
+				continue
+			}
+			if !strings.Contains(m.Value, placeholderValue) {
+				matchers = append(matchers, m.String())
+			}
+		}
+		if name == "" || strings.Contains(name, placeholderValue) {
+			return nil
+		}
+		usage, ok := inv[name]
+		if !ok {
+			usage = &MetricUsage{Name: name, Matchers: []string{}, Sources: []string{}}
+			inv[name] = usage
+		}
+		for _, m := range matchers {
+			usage.Matchers = appendUnique(usage.Matchers, m)
+		}
+		usage.Sources = appendUnique(usage.Sources, source)
+		return nil
+	})
+	return nil
+}
+
+func (inv inventory) list() []MetricUsage {
+	res := make([]MetricUsage, 0, len(inv))
+	for _, usage := range inv {
+		sort.Strings(usage.Matchers)
+		res = append(res, *usage)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// Inventory lists all metrics referenced by the prometheus targets of the dashboards and the rules of the
+// registered RecodingMaps. The Creator runs first, so the RecodingMaps are filled
+func (r *Runner) Inventory(in InventoryOptions) ([]MetricUsage, error) {
+	board, err := r.Dashboards(in.CreatorInput, in.Filter)
+	if err != nil {
+		return nil, err
+	}
+	inv := inventory{}
+	err = errors.Join(nil)
+	for _, b := range board {
+		lb, perr := parseLintBoard(b)
+		if perr != nil {
+			return nil, perr
+		}
+		for _, p := range lb.panels() {
+			for _, t := range p.Targets {
+				if isPrometheusTarget(p, t) {
+					err = errors.Join(err, inv.add(t["expr"].(string), lb.UID+" / "+p.Title))
+				}
+			}
+		}
+	}
+	recorded := map[string]bool{}
+	for _, m := range r.RecordingMaps {
+		for _, rule := range m.Rules() {
+			recorded[rule.Name] = true
+			err = errors.Join(err, inv.add(rule.Expr, "rule "+rule.Name))
+		}
+	}
+	res := inv.list()
+	for i := range res {
+		res[i].Recorded = recorded[res[i].Name]
+	}
+	return res, err
+}
+
+// ReadExposition returns the metric names of a prometheus text exposition. Histograms and summaries
+// also expose their _bucket, _sum and _count series
+func ReadExposition(r io.Reader) (map[string]bool, error) {
+	var p expfmt.TextParser
+	families, err := p.TextToMetricFamilies(r)
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]bool{}
+	for name, f := range families {
+		res[name] = true
+		switch f.GetType() {
+		case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+			res[name+"_bucket"] = true
+			fallthrough
+		case dto.MetricType_SUMMARY:
+			res[name+"_sum"] = true
+			res[name+"_count"] = true
+		}
+	}
+	return res, nil
+}
+
+// CompareInventory sets MetricUsage.Exposed by the exposed metric names
+func CompareInventory(metrics []MetricUsage, exposed map[string]bool) []MetricUsage {
+	for i := range metrics {
+		found := exposed[metrics[i].Name]
+		metrics[i].Exposed = &found
+	}
+	return metrics
+}
+
+func readMetricsURL(ctx context.Context, client *http.Client, url string) (map[string]bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/plain")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("could not read %s: HTTP status %d", url, resp.StatusCode)
+	}
+	return ReadExposition(resp.Body)
+}
+
+func printInventory(w io.Writer, metrics []MetricUsage) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tRECORDED\tEXPOSED\tSOURCES")
+	for _, m := range metrics {
+		exposed := "-"
+		if m.Exposed != nil {
+			exposed = strconv.FormatBool(*m.Exposed)
+		}
+		fmt.Fprintf(tw, "%s\t%t\t%s\t%s\n", m.Name, m.Recorded, exposed, strings.Join(m.Sources, ", "))
+	}
+	return tw.Flush()
+}
+
+func writeInventoryCSV(w io.Writer, metrics []MetricUsage) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"name", "recorded", "exposed", "matchers", "sources"}); err != nil {
+		return err
+	}
+	for _, m := range metrics {
+		exposed := ""
+		if m.Exposed != nil {
+			exposed = strconv.FormatBool(*m.Exposed)
+		}
+		record := []string{m.Name, strconv.FormatBool(m.Recorded), exposed, strings.Join(m.Matchers, ";"), strings.Join(m.Sources, ";")}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (r *Runner) inventoryAction(c *cli.Context) error {
+	metrics, err := r.Inventory(InventoryOptions{CreatorInput: r.CreatorInput(c), Filter: FilterFromValues(c)})
+	if err != nil {
+		return err
+	}
+	var exposed map[string]bool
+	if file := c.String(CliExposition); file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return fmt.Errorf("Error by %s: %w", file, err)
+		}
+		defer f.Close()
+		if exposed, err = ReadExposition(f); err != nil {
+			return fmt.Errorf("Error by %s: %w", file, err)
+		}
+	}
+	if url := c.String(CliMetricsURL); url != "" {
+		live, err := readMetricsURL(r.rootContext(), newHTTPClient(c), url)
+		if err != nil {
+			return err
+		}
+		if exposed == nil {
+			exposed = map[string]bool{}
+		}
+		for name := range live {
+			exposed[name] = true
+		}
+	}
+	if exposed != nil {
+		metrics = CompareInventory(metrics, exposed)
+	}
+	switch format := c.String(CliOutput); format {
+	case OutputText:
+		err = printInventory(os.Stdout, metrics)
+	case OutputCSV:
+		err = writeInventoryCSV(os.Stdout, metrics)
+	default:
+		err = writeStructured(os.Stdout, format, metrics)
+	}
+	if err != nil {
+		return err
+	}
+	missing := []string{}
+	for _, m := range metrics {
+		if m.Missing() {
+			missing = append(missing, m.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return cli.Exit(fmt.Sprintf("metrics not exposed: %s", strings.Join(missing, ", ")), 1)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package grabanaclistarter
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/K-Phoen/grabana/dashboard"
+	"github.com/K-Phoen/grabana/row"
+	"github.com/K-Phoen/grabana/timeseries"
+)
+
+func TestInventory(t *testing.T) {
+	r := &Runner{
+		Dashboard: func(in CreatorInput) ([]dashboard.Builder, error) {
+			return []dashboard.Builder{policyBoard(t, panelRow(row.WithTimeSeries("errors",
+				timeseries.WithPrometheusTarget(`sum(rate(http_requests_total{job="$job", code="500", instance=~"$host:.*"}[$__rate_interval]))`),
+				timeseries.WithPrometheusTarget(`$metric{job="api"}`),
+			)))}, nil
+		},
+	}
+	got, err := r.Inventory(InventoryOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []MetricUsage{{Name: "http_requests_total", Matchers: []string{`code="500"`}, Sources: []string{"policy / errors"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	var out bytes.Buffer
+	if err := writeInventoryCSV(&out, CompareInventory(got, map[string]bool{"http_requests_total": true})); err != nil {
+		t.Fatal(err)
+	}
+	if got[0].Exposed == nil || !*got[0].Exposed {
+		t.Errorf("http_requests_total is not exposed")
+	}
+	wantCSV := "name,recorded,exposed,matchers,sources\n" + `http_requests_total,false,true,"code=""500""",policy / errors` + "\n"
+	if out.String() != wantCSV {
+		t.Errorf("got csv %q, want %q", out.String(), wantCSV)
+	}
+}
+
+func TestInventoryRequestTimeout(t *testing.T) {
+	tests := []struct {
+		args []string
+		want time.Duration
+	}{
+		{want: 30 * time.Second},
+		{args: []string{"--request-timeout", "5s"}, want: 5 * time.Second},
+	}
+	for _, tt := range tests {
+		c := flagContext(t, inventoryFlags("app"), tt.args...)
+		if got := newHTTPClient(c).Timeout; got != tt.want {
+			t.Errorf("args %q: got timeout %s, want %s", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package grabanaclistarter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/K-Phoen/grabana/dashboard"
+	"github.com/cryptvault-cloud/helper"
+	"github.com/urfave/cli/v2"
+)
+
+// MaxUIDLength is the longest dashboard uid grafana accepts
+const MaxUIDLength = 40
+
+type lintGridPos struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+type lintPanel struct {
+	ID         int              `json:"id"`
+	Title      string           `json:"title"`
+	Type       string           `json:"type"`
+	GridPos    *lintGridPos     `json:"gridPos"`
+	Span       float64          `json:"span"`
+	Height     any              `json:"height"`
+	Datasource any              `json:"datasource"`
+	Targets    []map[string]any `json:"targets"`
+	// Panels of a collapsed row
+	Panels []lintPanel `json:"panels"`
+}
+
+// lintBoard is the part of the dashboard json the lint rules look at
+type lintBoard struct {
+	UID    string      `json:"uid"`
+	Title  string      `json:"title"`
+	Panels []lintPanel `json:"panels"`
+	Rows   []struct {
+		Title    string      `json:"title"`
+		Height   any         `json:"height"`
+		Collapse bool        `json:"collapse"`
+		Panels   []lintPanel `json:"panels"`
+	} `json:"rows"`
+	Templating struct {
+		List []lintVariable `json:"list"`
+	} `json:"templating"`
+}
+
+type lintVariable struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Current struct {
+		Value any `json:"value"`
+	} `json:"current"`
+}
+
+func (b lintBoard) panels() []lintPanel {
+	res := []lintPanel{}
+	for _, p := range b.Panels {
+		if p.Type == "row" {
+			res = append(res, p.Panels...)
+			continue
+		}
+		res = append(res, p)
+	}
+	for _, row := range b.Rows {
+		res = append(res, row.Panels...)
+	}
+	return res
+}
+
+// queryFields are the target fields holding a query
+var queryFields = []string{"expr", "query", "rawSql", "target"}
+
+// queries returns all query strings of the panel by target refId (or position)
+func (p lintPanel) queries() map[string]string {
+	res := map[string]string{}
+	for i, t := range p.Targets {
+		name, _ := t["refId"].(string)
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+		for _, field := range queryFields {
+			if q, ok := t[field].(string); ok && q != "" {
+				res[name] = q
+			}
+		}
+	}
+	return res
+}
+
+type lintRule func(board lintBoard) []Violation
+
+func lintViolation(board lintBoard, panel, rule, message string) Violation {
+	return Violation{Dashboard: board.UID, Panel: panel, Rule: rule, Severity: SeverityError, Message: message}
+}
+
+func lintUIDLength(board lintBoard) []Violation {
+	if len(board.UID) > MaxUIDLength {
+		return []Violation{lintViolation(board, "", "uid-length", fmt.Sprintf("uid has %d chars, grafana allows %d", len(board.UID), MaxUIDLength))}
+	}
+	return nil
+}
+
+func lintDuplicateRefIDs(board lintBoard) []Violation {
+	res := []Violation{}
+	for _, p := range board.panels() {
+		seen := map[string]bool{}
+		for _, t := range p.Targets {
+			refID, _ := t["refId"].(string)
+			if refID == "" {
+				continue
+			}
+			if seen[refID] {
+				res = append(res, lintViolation(board, p.Title, "duplicate-refid", fmt.Sprintf("refId %s is used more than once", refID)))
+			}
+			seen[refID] = true
+		}
+	}
+	return res
+}
+
+func overlaps(a, b LayoutPanel) bool {
+	return a.X < b.X+b.W && b.X < a.X+a.W && a.Y < b.Y+b.H && b.Y < a.Y+a.H
+}
+
+// lintOverlappingPanels checks the computed Layout, so panels with gridPos and legacy rows are both covered.
+// Panels of a collapsed row are only compared with each other
+func lintOverlappingPanels(board lintBoard) []Violation {
+	res := []Violation{}
+	check := func(layout []LayoutPanel) {
+		panels := []LayoutPanel{}
+		for _, p := range layout {
+			if !p.isRow() {
+				panels = append(panels, p)
+			}
+		}
+		for i := range panels {
+			for j := i + 1; j < len(panels); j++ {
+				a, b := panels[i], panels[j]
+				if overlaps(a, b) {
+					res = append(res, lintViolation(board, a.Title, "overlapping-panels", fmt.Sprintf("overlaps with panel %q", b.Title)))
+				}
+			}
+		}
+	}
+	top := board
+	top.Panels = []lintPanel{}
+	for _, p := range board.Panels {
+		if p.Type == "row" && len(p.Panels) > 0 {
+			check(boardLayout(lintBoard{Panels: p.Panels}))
+			p.Panels = nil
+		}
+		top.Panels = append(top.Panels, p)
+	}
+	for _, row := range board.Rows {
+		if row.Collapse {
+			row.Collapse = false
+			check(boardLayout(lintBoard{Rows: append(board.Rows[:0:0], row)}))
+		}
+	}
+	check(boardLayout(top))
+	return res
+}
+
+// lintRowSpans warns about legacy rows whose panel spans add up to more than 12, grafana wraps the panels
+// exceeding the row onto a new line
+func lintRowSpans(board lintBoard) []Violation {
+	res := []Violation{}
+	for _, row := range board.Rows {
+		span := 0.0
+		for _, p := range row.Panels {
+			span += p.Span
+		}
+		if span > gridColumns/2 {
+			v := lintViolation(board, row.Title, "row-span", fmt.Sprintf("panel spans add up to %g, panels beyond %d wrap onto a new line", span, gridColumns/2))
+			v.Severity = SeverityWarning
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// variableUsage matches $var, ${var:format} and [[var]]. Names starting with a digit are regex references like $1
+var variableUsage = regexp.MustCompile(`\$([A-Za-z_]\w*)|\$\{([A-Za-z_]\w*)(?:[:.][^}]*)?\}|\[\[([A-Za-z_]\w*)(?::[^\]]*)?\]\]`)
+
+var labelFuncCall = regexp.MustCompile(`\b(label_replace|label_join)\s*\(`)
+
+// labelFuncStrings returns the positions of the string arguments of label_replace and label_join. They hold
+// regex replacements like $name, which are no grafana variables
+func labelFuncStrings(query string) [][2]int {
+	res := [][2]int{}
+	for _, m := range labelFuncCall.FindAllStringIndex(query, -1) {
+		depth, braces := 1, 0
+		for i := m[1]; i < len(query) && depth > 0; i++ {
+			switch c := query[i]; c {
+			case '"', '\'', '`':
+				start := i
+				for i++; i < len(query) && query[i] != c; i++ {
+					if query[i] == '\\' && c != '`' {
+						i++
+					}
+				}
+				if depth == 1 && braces == 0 {
+					res = append(res, [2]int{start, i + 1})
+				}
+			case '(':
+				depth++
+			case ')':
+				depth--
+			case '{':
+				braces++
+			case '}':
+				braces--
+			}
+		}
+	}
+	return res
+}
+
+type variableMatch struct {
+	Start, End int
+	Name       string
+}
+
+// variableMatches returns all variable references of query
+func variableMatches(query string) []variableMatch {
+	res := []variableMatch{}
+	skip := labelFuncStrings(query)
+	for _, m := range variableUsage.FindAllStringSubmatchIndex(query, -1) {
+		if helper.Includes(skip, func(r [2]int) bool { return m[0] >= r[0] && m[1] <= r[1] }) {
+			continue
+		}
+		match := variableMatch{Start: m[0], End: m[1]}
+		for i := 2; i < len(m); i += 2 {
+			if m[i] >= 0 {
+				match.Name = query[m[i]:m[i+1]]
+			}
+		}
+		res = append(res, match)
+	}
+	return res
+}
+
+// usedVariables returns the names of all variables referenced in query
+func usedVariables(query string) []string {
+	res := []string{}
+	for _, m := range variableMatches(query) {
+		res = append(res, m.Name)
+	}
+	return res
+}
+
+func lintUndefinedVariables(board lintBoard) []Violation {
+	defined := map[string]bool{}
+	for _, v := range board.Templating.List {
+		defined[v.Name] = true
+	}
+	res := []Violation{}
+	for _, p := range board.panels() {
+		for ref, q := range p.queries() {
+			for _, name := range usedVariables(q) {
+				if !defined[name] && !strings.HasPrefix(name, "__") {
+					res = append(res, lintViolation(board, p.Title, "undefined-variable", fmt.Sprintf("target %s uses undefined variable $%s", ref, name)))
+				}
+			}
+		}
+	}
+	return res
+}
+
+var lintRules = []lintRule{
+	lintUIDLength,
+	lintDuplicateRefIDs,
+	lintOverlappingPanels,
+	lintRowSpans,
+	lintUndefinedVariables,
+	lintPromQL,
+}
+
+func parseLintBoard(b dashboard.Builder) (lintBoard, error) {
+	board := lintBoard{}
+	content, err := b.MarshalJSON()
+	if err != nil {
+		return board, fmt.Errorf("Error by %s: %w", b.Internal().UID, err)
+	}
+	if err := json.Unmarshal(content, &board); err != nil {
+		return board, fmt.Errorf("Error by %s: %w", b.Internal().UID, err)
+	}
+	return board, nil
+}
+
+// Lint runs the Creator offline and checks the json of every dashboard for structural problems
+func (r *Runner) Lint(in CreatorInput, filter Filter) ([]Violation, error) {
+	board, err := r.Dashboards(in, filter)
+	if err != nil {
+		return nil, err
+	}
+	res := []Violation{}
+	uids := map[string]string{}
+	for _, b := range board {
+		lb, err := parseLintBoard(b)
+		if err != nil {
+			return nil, err
+		}
+		if title, ok := uids[lb.UID]; ok {
+			res = append(res, lintViolation(lb, "", "duplicate-uid", fmt.Sprintf("uid is also used by dashboard %q", title)))
+		}
+		uids[lb.UID] = lb.Title
+		for _, rule := range lintRules {
+			res = append(res, rule(lb)...)
+		}
+	}
+	return res, nil
+}
+
+func (r *Runner) lintAction(c *cli.Context) error {
+	violations, err := r.Lint(r.CreatorInput(c), FilterFromValues(c))
+	if err != nil {
+		return err
+	}
+	if format := c.String(CliOutput); format != OutputText {
+		if err := writeStructured(os.Stdout, format, violations); err != nil {
+			return err
+		}
+	} else {
+		for _, v := range violations {
+			fmt.Println(v)
+		}
+	}
+	if errorCount := countErrors(violations); errorCount > 0 {
+		return cli.Exit(fmt.Sprintf("lint found %d errors", errorCount), 1)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package grabanaclistarter
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/K-Phoen/grabana/dashboard"
+	"github.com/K-Phoen/grabana/row"
+	"github.com/K-Phoen/grabana/target/prometheus"
+	"github.com/K-Phoen/grabana/timeseries"
+	"github.com/urfave/cli/v2"
+)
+
+func TestLintLayout(t *testing.T) {
+	tests := []struct {
+		name  string
+		board func(t *testing.T) lintBoard
+		want  []string
+	}{
+		{
+			name: "legacy row fits",
+			board: func(t *testing.T) lintBoard {
+				return builderLintBoard(t, panelRow(row.WithTimeSeries("a", timeseries.Span(6)), row.WithTimeSeries("b", timeseries.Span(6))))
+			},
+		},
+		{
+			name: "legacy row exceeds 12",
+			board: func(t *testing.T) lintBoard {
+				return builderLintBoard(t, panelRow(row.WithTimeSeries("a", timeseries.Span(8)), row.WithTimeSeries("b", timeseries.Span(8))))
+			},
+			want: []string{"[warning] policy / r: panel spans add up to 16, panels beyond 12 wrap onto a new line (row-span)"},
+		},
+		{
+			name: "gridPos overlap",
+			board: func(t *testing.T) lintBoard {
+				return jsonLintBoard(t, `{"uid":"policy","panels":[
+					{"title":"a","gridPos":{"x":0,"y":0,"w":12,"h":8}},
+					{"title":"b","gridPos":{"x":6,"y":4,"w":12,"h":8}}]}`)
+			},
+			want: []string{`[error] policy / a: overlaps with panel "b" (overlapping-panels)`},
+		},
+		{
+			name: "gridPos panel over legacy row",
+			board: func(t *testing.T) lintBoard {
+				return jsonLintBoard(t, `{"uid":"policy",
+					"panels":[{"title":"a","gridPos":{"x":0,"y":0,"w":24,"h":8}}],
+					"rows":[{"panels":[{"title":"b","span":12}]}]}`)
+			},
+		},
+		{
+			name: "collapsed row panels",
+			board: func(t *testing.T) lintBoard {
+				return jsonLintBoard(t, `{"uid":"policy","panels":[
+					{"title":"r","type":"row","gridPos":{"x":0,"y":0,"w":24,"h":1},"panels":[
+						{"title":"a","gridPos":{"x":0,"y":1,"w":24,"h":8}}]},
+					{"title":"b","gridPos":{"x":0,"y":1,"w":24,"h":8}}]}`)
+			},
+		},
+		{
+			name: "collapsed legacy row panels",
+			board: func(t *testing.T) lintBoard {
+				return jsonLintBoard(t, `{"uid":"policy","rows":[
+					{"title":"a","collapse":true,"panels":[{"title":"c","span":12}]},
+					{"title":"b","panels":[{"title":"d","span":12}]}]}`)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board := tt.board(t)
+			got := []string{}
+			for _, v := range append(lintOverlappingPanels(board), lintRowSpans(board)...) {
+				got = append(got, v.String())
+			}
+			if tt.want == nil {
+				tt.want = []string{}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func builderLintBoard(t *testing.T, options ...dashboard.Option) lintBoard {
+	t.Helper()
+	board, err := parseLintBoard(policyBoard(t, options...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return board
+}
+
+func jsonLintBoard(t *testing.T, content string) lintBoard {
+	t.Helper()
+	board := lintBoard{}
+	if err := json.Unmarshal([]byte(content), &board); err != nil {
+		t.Fatal(err)
+	}
+	return board
+}
+
+func TestLintActionCountsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []dashboard.Option
+		want    string
+	}{
+		{
+			name:    "warnings only",
+			options: []dashboard.Option{panelRow(row.WithTimeSeries("a", timeseries.Span(8)), row.WithTimeSeries("b", timeseries.Span(8)))},
+		},
+		{
+			name: "errors and warnings",
+			options: []dashboard.Option{panelRow(
+				row.WithTimeSeries("a", timeseries.Span(8),
+					timeseries.WithPrometheusTarget("up", prometheus.Ref("A")),
+					timeseries.WithPrometheusTarget("up", prometheus.Ref("A")),
+				),
+				row.WithTimeSeries("b", timeseries.Span(8),
+					timeseries.WithPrometheusTarget("up", prometheus.Ref("B")),
+					timeseries.WithPrometheusTarget("up", prometheus.Ref("B")),
+				),
+			)},
+			want: "lint found 2 errors",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Runner{
+				Dashboard: func(in CreatorInput) ([]dashboard.Builder, error) {
+					return []dashboard.Builder{policyBoard(t, tt.options...)}, nil
+				},
+			}
+			c := flagContext(t, append(append(creatorFlags("app"), filterFlags("app")...), outputFlag("app")))
+			var err error
+			out := captureOutput(t, &os.Stdout, func() { err = r.lintAction(c) })
+			if !strings.Contains(out, "(row-span)") {
+				t.Errorf("got output %q, want the row-span warning", out)
+			}
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("got error %v, want none for warnings", err)
+				}
+				return
+			}
+			var exit cli.ExitCoder
+			if !errors.As(err, &exit) || exit.ExitCode() != 1 || err.Error() != tt.want {
+				t.Errorf("got error %v, want exit code 1 with %q", err, tt.want)
+			}
+		})
+	}
+}
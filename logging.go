@@ -0,0 +1,88 @@
+package grabanaclistarter
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	CliLogLevel  CliValues = "log-level"
+	CliLogFormat CliValues = "log-format"
+)
+
+func logFlags(appName string) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    CliLogLevel,
+			EnvVars: []string{GetFlagEnvByFlagName(CliLogLevel, appName)},
+			Value:   "info",
+			Usage:   "debug|info|warn|error",
+		},
+		&cli.StringFlag{
+			Name:    CliLogFormat,
+			EnvVars: []string{GetFlagEnvByFlagName(CliLogFormat, appName)},
+			Value:   "text",
+			Usage:   "text|json",
+		},
+	}
+}
+
+// NewLogger creates a logger writing to stderr
+func NewLogger(level, format string) (*slog.Logger, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("Unknown log level %s", level)
+	}
+	opts := &slog.HandlerOptions{Level: l}
+	switch strings.ToLower(format) {
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts)), nil
+	case "text", "":
+		return slog.New(slog.NewTextHandler(os.Stderr, opts)), nil
+	}
+	return nil, fmt.Errorf("Unknown log format %s", format)
+}
+
+// initLogger configures Logger out of the flags, makes it the slog default and passes it to the RecordingMaps
+func (r *Runner) initLogger(c *cli.Context) error {
+	logger, err := NewLogger(c.String(CliLogLevel), c.String(CliLogFormat))
+	if err != nil {
+		return err
+	}
+	r.Logger = logger
+	slog.SetDefault(logger)
+	for _, m := range r.RecordingMaps {
+		m.SetLogger(logger)
+	}
+	return nil
+}
+
+func (r *Runner) logger() *slog.Logger {
+	if r.Logger == nil {
+		return slog.Default()
+	}
+	return r.Logger
+}
+
+// loggingTransport writes every grafana request with its response status as debug log
+type loggingTransport struct {
+	base   http.RoundTripper
+	logger *slog.Logger
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		t.logger.Debug("grafana request failed", "method", req.Method, "url", req.URL.String(), "duration", time.Since(start), "error", err)
+		return resp, err
+	}
+	t.logger.Debug("grafana request", "method", req.Method, "url", req.URL.String(), "status", resp.StatusCode, "duration", time.Since(start))
+	return resp, err
+}
@@ -0,0 +1,100 @@
+package grabanaclistarter
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fasibio/grabana_cli_starter/recordingrules"
+)
+
+// captureOutput redirects target (os.Stdout or os.Stderr) while fn runs and returns what was written
+func captureOutput(t *testing.T, target **os.File, fn func()) string {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := *target
+	*target = writer
+	defer func() { *target = original }()
+	fn()
+	writer.Close()
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, reader); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestNewLogger(t *testing.T) {
+	tests := []struct {
+		level, format string
+		wantErr       string
+		// want are the substrings of the output of one debug and one warn line
+		want []string
+		skip []string
+	}{
+		{level: "debug", format: "text", want: []string{"level=DEBUG msg=debug", "level=WARN msg=warn"}},
+		{level: "info", format: "", want: []string{"level=WARN msg=warn"}, skip: []string{"debug"}},
+		{level: "WARN", format: "JSON", want: []string{`"level":"WARN","msg":"warn"`}, skip: []string{"debug"}},
+		{level: "error", format: "json", skip: []string{"debug", "warn"}},
+		{level: "verbose", format: "text", wantErr: "Unknown log level verbose"},
+		{level: "info", format: "xml", wantErr: "Unknown log format xml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level+"/"+tt.format, func(t *testing.T) {
+			var logger *slog.Logger
+			var err error
+			got := captureOutput(t, &os.Stderr, func() {
+				logger, err = NewLogger(tt.level, tt.format)
+				if err == nil {
+					logger.Debug("debug")
+					logger.Warn("warn")
+				}
+			})
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("got error %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("output %q does not contain %q", got, w)
+				}
+			}
+			for _, s := range tt.skip {
+				if strings.Contains(got, `msg=`+s) || strings.Contains(got, `"msg":"`+s+`"`) {
+					t.Errorf("output %q contains %s", got, s)
+				}
+			}
+		})
+	}
+}
+
+func TestInitLoggerReachesRecordingMaps(t *testing.T) {
+	defaultLogger := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+	rules := recordingrules.NewRecordingMap(false)
+	r := &Runner{RecordingMaps: []*recordingrules.RecodingMap{&rules}}
+	c := flagContext(t, logFlags("app"), "--log-format", "json")
+	got := captureOutput(t, &os.Stderr, func() {
+		if err := r.initLogger(c); err != nil {
+			t.Fatal(err)
+		}
+		// the map has to use the Runner logger, not whatever is the default
+		slog.SetDefault(defaultLogger)
+		rules.AppendRule("a:up", "up")
+		rules.AppendRule("b:up", "up")
+	})
+	if !strings.Contains(got, `"msg":"Same expr found, reusing recording rule","rule":"a:up","for":"b:up"`) {
+		t.Errorf("got output %q, want the json log of the RecordingMap", got)
+	}
+}
@@ -0,0 +1,489 @@
+package grabanaclistarter
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/K-Phoen/grabana/dashboard"
+	"github.com/K-Phoen/grabana/graph"
+	"github.com/K-Phoen/grabana/singlestat"
+	"github.com/K-Phoen/grabana/stat"
+	"github.com/K-Phoen/grabana/timeseries"
+	"github.com/K-Phoen/sdk"
+	"github.com/urfave/cli/v2"
+)
+
+// WithPanelMigration registers MigrateDeprecatedPanels as mutator and logs the options it could not map as warnings
+func WithPanelMigration() Option {
+	return func(runner *Runner, app *cli.App) error {
+		runner.Mutators = append(runner.Mutators, MigrateDeprecatedPanels(func(v Violation) {
+			runner.logger().Warn("Panel migration incomplete", "dashboard", v.Dashboard, "panel", v.Panel, "message", v.Message)
+		}))
+		return nil
+	}
+}
+
+// MigrateDeprecatedPanels replaces graph panels by timeseries and singlestat panels by stat panels.
+// Queries, units, thresholds, legends and series overrides are kept. Every option without an equivalent
+// in the new panel is passed to report (may be nil), options left at the grabana defaults are not reported
+func MigrateDeprecatedPanels(report func(Violation)) DashboardMutator {
+	return func(b *dashboard.Builder) error {
+		for i, ref := range boardPanels(b.Internal()) {
+			var migrated *sdk.Panel
+			var unmapped []string
+			var err error
+			switch {
+			case ref.Panel.GraphPanel != nil:
+				migrated, unmapped, err = migrateGraph(ref.Panel)
+			case ref.Panel.SinglestatPanel != nil:
+				migrated, unmapped, err = migrateSinglestat(ref.Panel)
+			default:
+				continue
+			}
+			if err != nil {
+				return panelError(*b, i, ref.Panel, err)
+			}
+			for _, message := range unmapped {
+				if report != nil {
+					report(panelViolation(*b, ref.Panel, "panel-migration", SeverityWarning, message))
+				}
+			}
+			*ref.Panel = *migrated
+		}
+		return nil
+	}
+}
+
+// migratedCommon keeps id, position, links, repeat and description of the old panel
+func migratedCommon(old sdk.CommonPanel, panel *sdk.Panel) {
+	common := old
+	common.OfType = panel.OfType
+	common.Type = panel.Type
+	common.Renderer = panel.Renderer
+	common.IsNew = false
+	panel.CommonPanel = common
+}
+
+func fieldOverride(matcher, options string, properties ...sdk.FieldConfigOverrideProperty) sdk.FieldConfigOverride {
+	res := sdk.FieldConfigOverride{Properties: properties}
+	res.Matcher.ID = matcher
+	res.Matcher.Options = options
+	return res
+}
+
+func fixedColor(color string) sdk.FieldConfigOverrideProperty {
+	return sdk.FieldConfigOverrideProperty{ID: "color", Value: map[string]any{"mode": "fixed", "fixedColor": color}}
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// legacyThresholdColor returns the color grafana used for the colorMode of a graph threshold
+func legacyThresholdColor(t sdk.Threshold) string {
+	switch t.ColorMode {
+	case "critical":
+		return "red"
+	case "warning":
+		return "orange"
+	case "ok":
+		return "green"
+	}
+	if t.LineColor != "" {
+		return t.LineColor
+	}
+	if t.FillColor != "" {
+		return t.FillColor
+	}
+	return "red"
+}
+
+func migrateGraph(p *sdk.Panel) (*sdk.Panel, []string, error) {
+	g := p.GraphPanel
+	defaults, err := graph.New("")
+	if err != nil {
+		return nil, nil, err
+	}
+	def := defaults.Builder.GraphPanel
+	ts, err := timeseries.New(p.Title)
+	if err != nil {
+		return nil, nil, err
+	}
+	res := ts.Builder
+	migratedCommon(p.CommonPanel, res)
+	unmapped := []string{}
+	notSupported := func(option string) {
+		unmapped = append(unmapped, fmt.Sprintf("graph option %s has no timeseries equivalent", option))
+	}
+
+	res.TimeseriesPanel.Targets = g.Targets
+	fieldDefaults := &res.TimeseriesPanel.FieldConfig.Defaults
+	custom := &fieldDefaults.Custom
+
+	if len(g.Yaxes) > 0 {
+		left := g.Yaxes[0]
+		fieldDefaults.Unit = left.Format
+		if left.Decimals != 0 {
+			decimals := left.Decimals
+			fieldDefaults.Decimals = &decimals
+		}
+		if left.Min != nil && left.Min.Valid {
+			fieldDefaults.Min = floatPtr(left.Min.Value)
+		}
+		if left.Max != nil && left.Max.Valid {
+			fieldDefaults.Max = floatPtr(left.Max.Value)
+		}
+		custom.AxisLabel = left.Label
+		if left.LogBase > 1 {
+			custom.ScaleDistribution.Type = "log"
+			custom.ScaleDistribution.Log = left.LogBase
+		}
+		if !left.Show {
+			custom.AxisPlacement = "hidden"
+		}
+	}
+	if g.Decimals != nil {
+		decimals := *g.Decimals
+		fieldDefaults.Decimals = &decimals
+	}
+	if !g.Xaxis.Show && def.Xaxis.Show {
+		notSupported("xaxis.show")
+	}
+
+	switch {
+	case g.Bars:
+		custom.DrawStyle = "bars"
+	case !g.Lines && g.Points:
+		custom.DrawStyle = "points"
+	}
+	custom.ShowPoints = "never"
+	if g.Points {
+		custom.ShowPoints = "always"
+	}
+	custom.PointSize = int(g.Pointradius * 2)
+	custom.LineWidth = int(g.Linewidth)
+	custom.FillOpacity = g.Fill * 10
+	if g.SteppedLine {
+		custom.LineInterpolation = "stepAfter"
+	}
+	if g.Dashes != nil && *g.Dashes {
+		custom.LineStyle.Fill = "dash"
+	}
+	if g.Stack {
+		custom.Stacking.Group = "A"
+		custom.Stacking.Mode = "normal"
+		if g.Percentage {
+			custom.Stacking.Mode = "percent"
+		}
+	} else if g.Percentage {
+		notSupported("percentage without stack")
+	}
+	switch g.NullPointMode {
+	case "connected":
+		custom.SpanNulls = true
+	case "null", def.NullPointMode:
+	default:
+		notSupported("nullPointMode " + g.NullPointMode)
+	}
+
+	legend := &res.TimeseriesPanel.Options.Legend
+	show := g.Legend.Show
+	legend.Show = &show
+	legend.DisplayMode = "list"
+	switch {
+	case !g.Legend.Show:
+		legend.DisplayMode = "hidden"
+	case g.Legend.AlignAsTable:
+		legend.DisplayMode = "table"
+	}
+	legend.Placement = "bottom"
+	if g.Legend.RightSide {
+		legend.Placement = "right"
+	}
+	legend.Calcs = []string{}
+	if g.Legend.Values {
+		for _, calc := range []struct {
+			enabled bool
+			name    string
+		}{
+			{g.Legend.Min, "min"},
+			{g.Legend.Max, "max"},
+			{g.Legend.Avg, "mean"},
+			{g.Legend.Current, "lastNotNull"},
+			{g.Legend.Total, "sum"},
+		} {
+			if calc.enabled {
+				legend.Calcs = append(legend.Calcs, calc.name)
+			}
+		}
+	}
+	if g.Legend.HideEmpty && !def.Legend.HideEmpty {
+		notSupported("legend.hideEmpty")
+	}
+	if g.Legend.HideZero && !def.Legend.HideZero {
+		notSupported("legend.hideZero")
+	}
+	if g.Legend.SideWidth != nil {
+		notSupported("legend.sideWidth")
+	}
+
+	res.TimeseriesPanel.Options.Tooltip.Mode = "single"
+	if g.Tooltip.Shared {
+		res.TimeseriesPanel.Options.Tooltip.Mode = "multi"
+	}
+	if g.Tooltip.Sort != def.Tooltip.Sort {
+		notSupported("tooltip.sort")
+	}
+	if g.Tooltip.ValueType == "cumulative" {
+		notSupported("tooltip.value_type cumulative")
+	}
+
+	if len(g.Thresholds) > 0 {
+		thresholds := append([]sdk.Threshold{}, g.Thresholds...)
+		sort.SliceStable(thresholds, func(i, j int) bool { return thresholds[i].Value < thresholds[j].Value })
+		steps := []sdk.ThresholdStep{{Color: "transparent"}}
+		fill, line := false, false
+		for i, t := range thresholds {
+			fill, line = fill || t.Fill, line || t.Line
+			if t.Yaxis == "right" {
+				notSupported(fmt.Sprintf("threshold %v on the right y axis", t.Value))
+			}
+			switch {
+			case t.Op != "lt":
+				steps = append(steps, sdk.ThresholdStep{Color: legacyThresholdColor(t), Value: floatPtr(float64(t.Value))})
+			case i == 0:
+				steps[0].Color = legacyThresholdColor(t)
+				steps = append(steps, sdk.ThresholdStep{Color: "transparent", Value: floatPtr(float64(t.Value))})
+			default:
+				notSupported(fmt.Sprintf("threshold lt %v above another threshold", t.Value))
+			}
+		}
+		fieldDefaults.Thresholds = sdk.Thresholds{Mode: "absolute", Steps: steps}
+		switch {
+		case fill && line:
+			custom.ThresholdsStyle.Mode = "line+area"
+		case fill:
+			custom.ThresholdsStyle.Mode = "area"
+		case line:
+			custom.ThresholdsStyle.Mode = "line"
+		default:
+			custom.ThresholdsStyle.Mode = "off"
+		}
+	}
+
+	overrides := []sdk.FieldConfigOverride{}
+	if colors, ok := g.AliasColors.(map[string]any); ok {
+		names := make([]string, 0, len(colors))
+		for name := range colors {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if color, ok := colors[name].(string); ok {
+				overrides = append(overrides, fieldOverride("byName", name, fixedColor(color)))
+			}
+		}
+	}
+	for _, o := range g.SeriesOverrides {
+		override, notes := migrateSeriesOverride(o, g.Yaxes)
+		for _, note := range notes {
+			notSupported(note)
+		}
+		if len(override.Properties) > 0 {
+			overrides = append(overrides, override)
+		}
+	}
+	if g.FieldConfig != nil {
+		overrides = append(overrides, g.FieldConfig.Overrides...)
+	}
+	res.TimeseriesPanel.FieldConfig.Overrides = overrides
+
+	if g.TimeFrom != nil {
+		notSupported("timeFrom")
+	}
+	if g.TimeShift != nil {
+		notSupported("timeShift")
+	}
+	return res, unmapped, nil
+}
+
+// migrateSeriesOverride converts a graph series override to a field override, aliases like /regex/ match by regexp
+func migrateSeriesOverride(o sdk.SeriesOverride, yaxes []sdk.Axis) (sdk.FieldConfigOverride, []string) {
+	res := fieldOverride("byName", o.Alias)
+	if len(o.Alias) > 1 && strings.HasPrefix(o.Alias, "/") && strings.HasSuffix(o.Alias, "/") {
+		res = fieldOverride("byRegexp", o.Alias[1:len(o.Alias)-1])
+	}
+	unmapped := []string{}
+	add := func(id string, value any) {
+		res.Properties = append(res.Properties, sdk.FieldConfigOverrideProperty{ID: id, Value: value})
+	}
+	if o.Color != nil {
+		res.Properties = append(res.Properties, fixedColor(*o.Color))
+	}
+	if o.Fill != nil {
+		add("custom.fillOpacity", *o.Fill*10)
+	}
+	if o.LineWidth != nil {
+		add("custom.lineWidth", *o.LineWidth)
+	}
+	switch {
+	case o.Bars != nil && *o.Bars:
+		add("custom.drawStyle", "bars")
+	case o.Lines != nil && *o.Lines:
+		add("custom.drawStyle", "line")
+	case o.Lines != nil:
+		add("custom.lineWidth", 0)
+	}
+	if o.Dashes != nil && *o.Dashes {
+		add("custom.lineStyle", map[string]any{"fill": "dash", "dash": []int{10, 10}})
+	}
+	if o.Legend != nil && !*o.Legend {
+		add("custom.hideFrom", map[string]bool{"legend": true, "tooltip": false, "viz": false})
+	}
+	if o.YAxis != nil && *o.YAxis == 2 {
+		add("custom.axisPlacement", "right")
+		if len(yaxes) > 1 {
+			add("unit", yaxes[1].Format)
+		}
+	}
+	if o.Stack != nil {
+		switch {
+		case o.Stack.Value != "":
+			add("custom.stacking", map[string]string{"group": o.Stack.Value, "mode": "normal"})
+		case o.Stack.Flag:
+			add("custom.stacking", map[string]string{"group": "A", "mode": "normal"})
+		default:
+			add("custom.stacking", map[string]string{"group": "A", "mode": "none"})
+		}
+	}
+	if o.Transform != nil {
+		add("custom.transform", *o.Transform)
+	}
+	for option, set := range map[string]bool{
+		"zindex":        o.ZIndex != nil,
+		"fillBelowTo":   o.FillBelowTo != nil,
+		"nullPointMode": o.NullPointMode != nil,
+	} {
+		if set {
+			unmapped = append(unmapped, fmt.Sprintf("seriesOverrides[%s].%s", o.Alias, option))
+		}
+	}
+	sort.Strings(unmapped)
+	return res, unmapped
+}
+
+// singlestatCalcs maps the valueName of a singlestat panel to the reducer of a stat panel
+var singlestatCalcs = map[string]string{
+	"min":     "min",
+	"max":     "max",
+	"avg":     "mean",
+	"current": "lastNotNull",
+	"total":   "sum",
+	"first":   "firstNotNull",
+	"delta":   "delta",
+	"diff":    "diff",
+	"range":   "range",
+}
+
+func migrateSinglestat(p *sdk.Panel) (*sdk.Panel, []string, error) {
+	s := p.SinglestatPanel
+	defaults, err := singlestat.New("")
+	if err != nil {
+		return nil, nil, err
+	}
+	def := defaults.Builder.SinglestatPanel
+	st, err := stat.New(p.Title)
+	if err != nil {
+		return nil, nil, err
+	}
+	res := st.Builder
+	migratedCommon(p.CommonPanel, res)
+	unmapped := []string{}
+	notSupported := func(option string) {
+		unmapped = append(unmapped, fmt.Sprintf("singlestat option %s has no stat equivalent", option))
+	}
+
+	res.StatPanel.Targets = s.Targets
+	fieldDefaults := &res.StatPanel.FieldConfig.Defaults
+	fieldDefaults.Unit = s.Format
+	if s.Decimals != 0 {
+		decimals := s.Decimals
+		fieldDefaults.Decimals = &decimals
+	}
+	if calc, ok := singlestatCalcs[s.ValueName]; ok {
+		res.StatPanel.Options.ReduceOptions.Calcs = []string{calc}
+	} else if s.ValueName != "" {
+		notSupported("valueName " + s.ValueName)
+	}
+
+	switch {
+	case s.ColorBackground:
+		res.StatPanel.Options.ColorMode = "background"
+	case s.ColorValue:
+		res.StatPanel.Options.ColorMode = "value"
+	default:
+		res.StatPanel.Options.ColorMode = "none"
+	}
+	if len(s.Colors) > 0 {
+		steps := []sdk.ThresholdStep{{Color: s.Colors[0]}}
+		if strings.TrimSpace(s.Thresholds) != "" {
+			for i, value := range strings.Split(s.Thresholds, ",") {
+				v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err != nil || i+1 >= len(s.Colors) {
+					notSupported("thresholds " + s.Thresholds)
+					steps = steps[:1]
+					break
+				}
+				steps = append(steps, sdk.ThresholdStep{Color: s.Colors[i+1], Value: floatPtr(v)})
+			}
+		}
+		fieldDefaults.Thresholds = sdk.Thresholds{Mode: "absolute", Steps: steps}
+	}
+
+	res.StatPanel.Options.GraphMode = "none"
+	if s.SparkLine.Show {
+		res.StatPanel.Options.GraphMode = "area"
+		if s.SparkLine.Full {
+			notSupported("sparkline.full")
+		}
+		if s.SparkLine.YMin != nil || s.SparkLine.YMax != nil {
+			notSupported("sparkline.ymin/ymax")
+		}
+		if deref(s.SparkLine.LineColor) != deref(def.SparkLine.LineColor) || deref(s.SparkLine.FillColor) != deref(def.SparkLine.FillColor) {
+			notSupported("sparkline colors")
+		}
+	}
+
+	for _, m := range s.ValueMaps {
+		if m.Value == "null" {
+			fieldDefaults.NoValue = m.TextType
+			continue
+		}
+		notSupported(fmt.Sprintf("valueMaps %s -> %s", m.Value, m.TextType))
+	}
+	if len(s.RangeMaps) > 0 {
+		notSupported("rangeMaps")
+	}
+	if deref(s.Prefix) != "" {
+		notSupported("prefix")
+	}
+	if deref(s.Postfix) != "" {
+		notSupported("postfix")
+	}
+	if s.Gauge.Show {
+		notSupported("gauge, use a gauge panel")
+	}
+	if s.ValueFontSize != def.ValueFontSize {
+		notSupported("valueFontSize")
+	}
+	return res, unmapped, nil
+}
@@ -0,0 +1,200 @@
+package grabanaclistarter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/K-Phoen/grabana/graph"
+	"github.com/K-Phoen/grabana/graph/series"
+	"github.com/K-Phoen/grabana/row"
+	"github.com/K-Phoen/grabana/singlestat"
+	"github.com/K-Phoen/sdk"
+	"github.com/fasibio/grabana_cli_starter/recordingrules"
+)
+
+// thresholds sets legacy graph thresholds, grabana has no option for them
+func thresholds(values ...sdk.Threshold) graph.Option {
+	return func(g *graph.Graph) error {
+		g.Builder.GraphPanel.Thresholds = values
+		return nil
+	}
+}
+
+func steps(t *testing.T, th sdk.Thresholds) string {
+	t.Helper()
+	content, err := json.Marshal(th.Steps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestMigrateDeprecatedPanels(t *testing.T) {
+	rules := recordingrules.NewRecordingMap(false)
+	tests := []struct {
+		name  string
+		panel row.Option
+		// check compares the migrated panel with the old one
+		check func(t *testing.T, old, migrated *sdk.Panel)
+		want  []string
+	}{
+		{
+			name: "graph",
+			panel: row.WithGraph("requests",
+				graph.DataSource("prom"),
+				graph.WithPrometheusTarget(`sum(rate(http_requests_total[5m]))`),
+				graph.Fill(3),
+				graph.LineWidth(2),
+				thresholds(sdk.Threshold{Value: 80, Op: "gt", ColorMode: "critical", Fill: true, Line: true}),
+			),
+			check: func(t *testing.T, old, migrated *sdk.Panel) {
+				custom := migrated.TimeseriesPanel.FieldConfig.Defaults.Custom
+				if custom.FillOpacity != 30 || custom.LineWidth != 2 {
+					t.Errorf("got fillOpacity %d and lineWidth %d, want 30 and 2", custom.FillOpacity, custom.LineWidth)
+				}
+				if got := steps(t, migrated.TimeseriesPanel.FieldConfig.Defaults.Thresholds); got != `[{"color":"transparent","value":null},{"color":"red","value":80}]` {
+					t.Errorf("got threshold steps %s", got)
+				}
+				if custom.ThresholdsStyle.Mode != "line+area" {
+					t.Errorf("got thresholds style %s, want line+area", custom.ThresholdsStyle.Mode)
+				}
+				if !reflect.DeepEqual(migrated.TimeseriesPanel.Targets, old.GraphPanel.Targets) {
+					t.Errorf("got targets %+v, want %+v", migrated.TimeseriesPanel.Targets, old.GraphPanel.Targets)
+				}
+			},
+		},
+		{
+			name:  "graph of recording rules",
+			panel: row.WithGraph("recorded", graph.DataSource("prom"), rules.WithGraph("job:requests:rate5m", `sum by (job) (rate(http_requests_total[5m]))`)),
+			check: func(t *testing.T, old, migrated *sdk.Panel) {
+				targets := migrated.TimeseriesPanel.Targets
+				if len(targets) != 2 || !reflect.DeepEqual(targets, old.GraphPanel.Targets) {
+					t.Errorf("got targets %+v, want the recorded and the raw query %+v", targets, old.GraphPanel.Targets)
+				}
+			},
+		},
+		{
+			name: "graph options without equivalent",
+			panel: row.WithGraph("unmapped",
+				graph.SeriesOverride(series.Alias("/5xx/"), series.Color("red")),
+				thresholds(sdk.Threshold{Value: 10, Op: "gt", Yaxis: "right"}, sdk.Threshold{Value: 20, Op: "lt"}),
+			),
+			check: func(t *testing.T, old, migrated *sdk.Panel) {
+				overrides := migrated.TimeseriesPanel.FieldConfig.Overrides
+				if len(overrides) != 1 || overrides[0].Matcher.ID != "byRegexp" || overrides[0].Matcher.Options != "5xx" {
+					t.Errorf("got overrides %+v, want one byRegexp 5xx", overrides)
+				}
+			},
+			want: []string{
+				"graph option threshold 10 on the right y axis has no timeseries equivalent",
+				"graph option threshold lt 20 above another threshold has no timeseries equivalent",
+			},
+		},
+		{
+			name: "singlestat",
+			panel: row.WithSingleStat("errors",
+				singlestat.DataSource("prom"),
+				singlestat.WithPrometheusTarget(`sum(errors_total)`),
+				singlestat.Unit("short"),
+				singlestat.Thresholds([2]string{"50", "80"}),
+				singlestat.Colors([3]string{"green", "orange", "red"}),
+				singlestat.ColorBackground(),
+				singlestat.ValuesToText([]singlestat.ValueMap{{Value: "null", Text: "none"}}),
+			),
+			check: func(t *testing.T, old, migrated *sdk.Panel) {
+				defaults := migrated.StatPanel.FieldConfig.Defaults
+				if defaults.Unit != "short" || defaults.NoValue != "none" {
+					t.Errorf("got unit %q and noValue %q, want short and none", defaults.Unit, defaults.NoValue)
+				}
+				if got := steps(t, defaults.Thresholds); got != `[{"color":"green","value":null},{"color":"orange","value":50},{"color":"red","value":80}]` {
+					t.Errorf("got threshold steps %s", got)
+				}
+				if migrated.StatPanel.Options.ColorMode != "background" {
+					t.Errorf("got color mode %s, want background", migrated.StatPanel.Options.ColorMode)
+				}
+				if !reflect.DeepEqual(migrated.StatPanel.Targets, old.SinglestatPanel.Targets) {
+					t.Errorf("got targets %+v, want %+v", migrated.StatPanel.Targets, old.SinglestatPanel.Targets)
+				}
+			},
+		},
+		{
+			name:  "singlestat of recording rules",
+			panel: row.WithSingleStat("recorded", singlestat.DataSource("prom"), singlestat.SparkLine(), rules.WithSingleStat("job:errors:sum", `sum by (job) (errors_total)`)),
+			check: func(t *testing.T, old, migrated *sdk.Panel) {
+				targets := migrated.StatPanel.Targets
+				if len(targets) != 2 || !reflect.DeepEqual(targets, old.SinglestatPanel.Targets) {
+					t.Errorf("got targets %+v, want the recorded and the raw query %+v", targets, old.SinglestatPanel.Targets)
+				}
+				if migrated.StatPanel.Options.GraphMode != "area" {
+					t.Errorf("got graph mode %s, want area", migrated.StatPanel.Options.GraphMode)
+				}
+			},
+		},
+		{
+			name: "singlestat options without equivalent",
+			panel: row.WithSingleStat("unmapped",
+				singlestat.FullSparkLine(),
+				singlestat.Prefix("~"),
+				singlestat.ValuesToText([]singlestat.ValueMap{{Value: "1", Text: "up"}}),
+			),
+			want: []string{
+				"singlestat option sparkline.full has no stat equivalent",
+				"singlestat option valueMaps 1 -> up has no stat equivalent",
+				"singlestat option prefix has no stat equivalent",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := policyBoard(t, panelRow(tt.panel))
+			refs := boardPanels(b.Internal())
+			if len(refs) != 1 {
+				t.Fatalf("got %d panels, want 1", len(refs))
+			}
+			old := *refs[0].Panel
+			got := []string{}
+			err := MigrateDeprecatedPanels(func(v Violation) {
+				if v.Rule != "panel-migration" || v.Severity != SeverityWarning {
+					t.Errorf("got violation %s, want a panel-migration warning", v)
+				}
+				got = append(got, v.Message)
+			})(&b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			migrated := boardPanels(b.Internal())[0].Panel
+			if migrated.GraphPanel != nil || migrated.SinglestatPanel != nil {
+				t.Fatalf("panel %s is still a %s", migrated.Title, migrated.Type)
+			}
+			if !reflect.DeepEqual(migrated.Datasource, old.Datasource) || migrated.ID != old.ID || migrated.Title != old.Title {
+				t.Errorf("got datasource %+v, id %d, title %s, want %+v, %d, %s", migrated.Datasource, migrated.ID, migrated.Title, old.Datasource, old.ID, old.Title)
+			}
+			if tt.check != nil {
+				tt.check(t, &old, migrated)
+			}
+			if tt.want == nil {
+				tt.want = []string{}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got unmapped %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPanelError(t *testing.T) {
+	b := policyBoard(t, panelRow(row.WithGraph("requests"), row.WithGraph("errors")))
+	refs := boardPanels(b.Internal())
+	failed := errors.New("failed")
+	err := panelError(b, 1, refs[1].Panel, failed)
+	if want := fmt.Sprintf("Error by policy / errors (panel id %d): failed", refs[1].Panel.ID); err.Error() != want || !errors.Is(err, failed) {
+		t.Errorf("got %v, want %s", err, want)
+	}
+	refs[1].Panel.ID = 0
+	if got, want := panelError(b, 1, refs[1].Panel, failed).Error(), "Error by policy / errors (panel #1): failed"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,82 @@
+package grabanaclistarter
+
+import (
+	"fmt"
+
+	"github.com/K-Phoen/grabana/dashboard"
+	"github.com/K-Phoen/sdk"
+	"github.com/cryptvault-cloud/helper"
+	"github.com/urfave/cli/v2"
+)
+
+// DashboardMutator changes a dashboard after the Creator built it. Every dashboard.Option
+// (e.g. dashboard.Timezone, dashboard.AutoRefresh, dashboard.SharedCrossHair) can be used as mutator
+type DashboardMutator = dashboard.Option
+
+// WithDashboardMutator registers mutators applied to every dashboard in registration order,
+// before plan, apply, toYaml and all other commands see them
+func WithDashboardMutator(mutators ...DashboardMutator) Option {
+	return func(runner *Runner, app *cli.App) error {
+		runner.Mutators = append(runner.Mutators, mutators...)
+		return nil
+	}
+}
+
+// AppendTags adds tags missing at the dashboard (dashboard.Tags replaces all tags)
+func AppendTags(tags ...string) DashboardMutator {
+	return func(b *dashboard.Builder) error {
+		board := b.Internal()
+		for _, t := range tags {
+			if !helper.Includes(board.Tags, func(existing string) bool { return existing == t }) {
+				board.Tags = append(board.Tags, t)
+			}
+		}
+		return nil
+	}
+}
+
+// AppendExternalLinks adds links missing at the dashboard, a link with the same title and url is kept once
+// (dashboard.ExternalLinks replaces all links)
+func AppendExternalLinks(links ...dashboard.ExternalLink) DashboardMutator {
+	return func(b *dashboard.Builder) error {
+		existing := b.Internal().Links
+		if err := dashboard.ExternalLinks(links...)(b); err != nil {
+			return err
+		}
+		added := b.Internal().Links
+		b.Internal().Links = existing
+		for _, l := range added {
+			if !hasLink(b.Internal().Links, l) {
+				b.Internal().Links = append(b.Internal().Links, l)
+			}
+		}
+		return nil
+	}
+}
+
+func linkURL(l sdk.Link) string {
+	if l.URL == nil {
+		return ""
+	}
+	return *l.URL
+}
+
+func hasLink(links []sdk.Link, link sdk.Link) bool {
+	for _, l := range links {
+		if l.Title == link.Title && linkURL(l) == linkURL(link) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Runner) mutate(board []dashboard.Builder) error {
+	for i := range board {
+		for _, m := range r.Mutators {
+			if err := m(&board[i]); err != nil {
+				return fmt.Errorf("Error by %s: %w", board[i].Internal().UID, err)
+			}
+		}
+	}
+	return nil
+}
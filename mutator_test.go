@@ -0,0 +1,67 @@
+package grabanaclistarter
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/K-Phoen/grabana/dashboard"
+)
+
+func TestDashboardMutators(t *testing.T) {
+	runbook := dashboard.ExternalLink{Title: "Runbook", URL: "https://runbooks.example.com/api"}
+	logs := dashboard.ExternalLink{Title: "Logs", URL: "https://logs.example.com"}
+	r := &Runner{
+		Dashboard: func(in CreatorInput) ([]dashboard.Builder, error) {
+			b, err := dashboard.New("API", dashboard.UID("api"), dashboard.Tags([]string{"team"}), dashboard.ExternalLinks(runbook))
+			return []dashboard.Builder{b}, err
+		},
+	}
+	err := WithDashboardMutator(
+		AppendTags("team", "owner:sre"),
+		AppendTags("owner:sre", "tier:1"),
+		AppendExternalLinks(runbook, logs),
+		AppendExternalLinks(logs, dashboard.ExternalLink{Title: "Logs", URL: "https://logs.example.com/api"}),
+	)(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every run builds the dashboards again, the mutators must not pile up
+	for run := 0; run < 2; run++ {
+		board, err := r.Dashboards(CreatorInput{}, Filter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(board) != 1 {
+			t.Fatalf("got %d dashboards, want 1", len(board))
+		}
+		if got, want := board[0].Internal().Tags, []string{"team", "owner:sre", "tier:1"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("run %d: got tags %q, want %q", run, got, want)
+		}
+		got := []string{}
+		for _, l := range board[0].Internal().Links {
+			got = append(got, l.Title+" "+linkURL(l))
+		}
+		want := []string{"Runbook https://runbooks.example.com/api", "Logs https://logs.example.com", "Logs https://logs.example.com/api"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("run %d: got links %q, want %q", run, got, want)
+		}
+	}
+}
+
+func TestDashboardMutatorError(t *testing.T) {
+	r := &Runner{
+		Dashboard: func(in CreatorInput) ([]dashboard.Builder, error) {
+			b, err := dashboard.New("API", dashboard.UID("api"))
+			return []dashboard.Builder{b}, err
+		},
+	}
+	failed := errors.New("failed")
+	if err := WithDashboardMutator(func(b *dashboard.Builder) error { return failed })(r, nil); err != nil {
+		t.Fatal(err)
+	}
+	_, err := r.Dashboards(CreatorInput{}, Filter{})
+	if !errors.Is(err, failed) || err.Error() != "Error by api: failed" {
+		t.Errorf("got error %v, want Error by api: failed", err)
+	}
+}
@@ -0,0 +1,108 @@
+package grabanaclistarter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	CliOrgID   CliValues = "org-id"
+	CliOrgName CliValues = "org-name"
+	CliDevOrg  CliValues = "org"
+)
+
+func orgFlags(appName string) []cli.Flag {
+	return []cli.Flag{
+		&cli.IntFlag{
+			Name:    CliOrgID,
+			EnvVars: []string{GetFlagEnvByFlagName(CliOrgID, appName)},
+			Usage:   "grafana organisation id to work in (default: the one of the credentials)",
+		},
+		&cli.StringFlag{
+			Name:    CliOrgName,
+			EnvVars: []string{GetFlagEnvByFlagName(CliOrgName, appName)},
+			Usage:   "grafana organisation name to work in (needs server admin permission to resolve)",
+		},
+	}
+}
+
+// orgTransport selects the grafana organisation for every request
+type orgTransport struct {
+	base  http.RoundTripper
+	orgID int
+}
+
+func (t *orgTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("X-Grafana-Org-Id", strconv.Itoa(t.orgID))
+	return t.base.RoundTrip(req)
+}
+
+func withOrg(client *http.Client, orgID int) {
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	client.Transport = &orgTransport{base: base, orgID: orgID}
+}
+
+// OrgByName resolves the id of a grafana organisation
+func (r *Runner) OrgByName(ctx context.Context, name string) (int, error) {
+	org := struct {
+		ID int `json:"id"`
+	}{}
+	err := r.grafanaRequest(ctx, http.MethodGet, "/api/orgs/name/"+url.PathEscape(name), nil, &org)
+	if isNotFound(err) {
+		return 0, fmt.Errorf("Organisation %s not found", name)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("Could not resolve organisation %s: %w", name, err)
+	}
+	return org.ID, nil
+}
+
+// UseOrg makes every following request of Client and HTTPClient work in the given organisation
+func (r *Runner) UseOrg(orgID int) error {
+	if r.HTTPClient == nil {
+		return fmt.Errorf("Runner.HTTPClient is not set")
+	}
+	withOrg(r.HTTPClient, orgID)
+	r.OrgID = orgID
+	return nil
+}
+
+// selectOrg applies --org-id or --org-name
+func (r *Runner) selectOrg(c *cli.Context) error {
+	orgID, orgName := c.Int(CliOrgID), c.String(CliOrgName)
+	if orgID != 0 && orgName != "" {
+		return fmt.Errorf("Only one of --%s and --%s is allowed", CliOrgID, CliOrgName)
+	}
+	if orgName != "" {
+		var err error
+		if orgID, err = r.OrgByName(r.Ctx, orgName); err != nil {
+			return err
+		}
+	}
+	if orgID == 0 {
+		return nil
+	}
+	return r.UseOrg(orgID)
+}
+
+// createDevOrg creates the organisation in the dev grafana and switches client to it
+func createDevOrg(ctx context.Context, client *http.Client, server, name string) (int, error) {
+	org := struct {
+		OrgID int `json:"orgId"`
+	}{}
+	err := grafanaRequest(ctx, client, server, http.MethodPost, "/api/orgs", map[string]string{"name": name}, &org)
+	if err != nil {
+		return 0, fmt.Errorf("error create organisation %s: %w", name, err)
+	}
+	withOrg(client, org.OrgID)
+	return org.OrgID, nil
+}
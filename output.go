@@ -0,0 +1,98 @@
+package grabanaclistarter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+const CliOutput CliValues = "output"
+
+type OutputFormat = string
+
+const (
+	OutputText OutputFormat = "text"
+	OutputJSON OutputFormat = "json"
+	OutputYAML OutputFormat = "yaml"
+	// OutputCSV is only supported by inventory
+	OutputCSV OutputFormat = "csv"
+)
+
+func outputFlag(appName string) cli.Flag {
+	return &cli.StringFlag{
+		Name:    CliOutput,
+		Aliases: []string{"o"},
+		EnvVars: []string{GetFlagEnvByFlagName(CliOutput, appName)},
+		Value:   OutputText,
+		Usage:   "output format text|json|yaml|csv (csv for inventory only)",
+		Action: func(c *cli.Context, v string) error {
+			switch v {
+			case OutputText, OutputJSON, OutputYAML, OutputCSV:
+				return nil
+			}
+			return fmt.Errorf("Unknown output format %s", v)
+		},
+	}
+}
+
+// Report is the machine readable form of a DashboardResult
+type Report struct {
+	UID     string `json:"uid" yaml:"uid"`
+	Title   string `json:"title" yaml:"title"`
+	Folder  string `json:"folder" yaml:"folder"`
+	Action  string `json:"action" yaml:"action"`
+	Status  string `json:"status" yaml:"status"`
+	URL     string `json:"url,omitempty" yaml:"url,omitempty"`
+	Version int    `json:"version,omitempty" yaml:"version,omitempty"`
+	Error   string `json:"error,omitempty" yaml:"error,omitempty"`
+	// Violations of the Validators
+	Violations []Violation `json:"violations,omitempty" yaml:"violations,omitempty"`
+	// Dashboard is the json model (plan only)
+	Dashboard any `json:"dashboard,omitempty" yaml:"dashboard,omitempty"`
+}
+
+func NewReport(res DashboardResult) Report {
+	report := Report{
+		UID:        res.UID,
+		Title:      res.Title,
+		Folder:     res.Folder,
+		Action:     res.Action,
+		Status:     string(res.Status),
+		URL:        res.URL,
+		Version:    res.Version,
+		Violations: res.Violations,
+	}
+	if res.Err != nil {
+		report.Error = strings.TrimSpace(res.Err.Error())
+	}
+	if len(res.Model) > 0 {
+		_ = json.Unmarshal(res.Model, &report.Dashboard)
+	}
+	return report
+}
+
+// writeStructured writes v as json or yaml
+func writeStructured(w io.Writer, format OutputFormat, v any) error {
+	switch format {
+	case OutputYAML:
+		return yaml.NewEncoder(w).Encode(v)
+	case OutputCSV:
+		return fmt.Errorf("Output format %s is only supported by inventory", format)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// writeReports writes the results as json or yaml list of Report
+func writeReports(w io.Writer, format OutputFormat, results []DashboardResult) error {
+	reports := make([]Report, 0, len(results))
+	for _, res := range results {
+		reports = append(reports, NewReport(res))
+	}
+	return writeStructured(w, format, reports)
+}
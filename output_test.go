@@ -0,0 +1,29 @@
+package grabanaclistarter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestReportError(t *testing.T) {
+	results := []DashboardResult{
+		{UID: "a", Status: StatusFailed, Err: fmt.Errorf("Could not create dashboard: %w", fmt.Errorf("could not query grafana: {}\n (HTTP status 500)\n"))},
+		{UID: "b", Status: StatusDone},
+	}
+	var out bytes.Buffer
+	if err := writeReports(&out, OutputJSON, results); err != nil {
+		t.Fatal(err)
+	}
+	reports := []map[string]any{}
+	if err := json.Unmarshal(out.Bytes(), &reports); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Could not create dashboard: could not query grafana: {}\n (HTTP status 500)"; reports[0]["error"] != want {
+		t.Errorf("got error %q, want %q", reports[0]["error"], want)
+	}
+	if _, ok := reports[1]["error"]; ok {
+		t.Errorf("done dashboard has an error: %v", reports[1])
+	}
+}
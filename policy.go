@@ -0,0 +1,225 @@
+package grabanaclistarter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/K-Phoen/grabana/dashboard"
+	"github.com/K-Phoen/sdk"
+	"github.com/urfave/cli/v2"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// Violation is one finding of a Validator
+type Violation struct {
+	Dashboard string   `json:"dashboard" yaml:"dashboard"`
+	Panel     string   `json:"panel,omitempty" yaml:"panel,omitempty"`
+	Rule      string   `json:"rule" yaml:"rule"`
+	Severity  Severity `json:"severity" yaml:"severity"`
+	Message   string   `json:"message" yaml:"message"`
+}
+
+func (v Violation) String() string {
+	location := v.Dashboard
+	if v.Panel != "" {
+		location += " / " + v.Panel
+	}
+	return fmt.Sprintf("[%s] %s: %s (%s)", v.Severity, location, v.Message, v.Rule)
+}
+
+// Validator checks one dashboard
+type Validator func(b dashboard.Builder) []Violation
+
+// WithValidator registers validators running on every dashboard before apply and plan. Violations with
+// SeverityError fail apply
+func WithValidator(validators ...Validator) Option {
+	return func(runner *Runner, app *cli.App) error {
+		runner.Validators = append(runner.Validators, validators...)
+		return nil
+	}
+}
+
+// PanelRef is a panel with the title of the row it is placed in
+type PanelRef struct {
+	Row   string
+	Panel *sdk.Panel
+}
+
+// boardPanels returns all panels of the dashboard including the ones of collapsed rows, row panels excluded
+func boardPanels(board *sdk.Board) []PanelRef {
+	res := []PanelRef{}
+	for _, p := range board.Panels {
+		if p.Type != "row" {
+			res = append(res, PanelRef{Panel: p})
+			continue
+		}
+		if p.RowPanel != nil {
+			for i := range p.RowPanel.Panels {
+				res = append(res, PanelRef{Row: p.Title, Panel: &p.RowPanel.Panels[i]})
+			}
+		}
+	}
+	for _, row := range board.Rows {
+		for i := range row.Panels {
+			res = append(res, PanelRef{Row: row.Title, Panel: &row.Panels[i]})
+		}
+	}
+	return res
+}
+
+// targetName is the refId or the position if the target has no refId
+func targetName(t sdk.Target, i int) string {
+	if t.RefID != "" {
+		return t.RefID
+	}
+	return fmt.Sprintf("#%d", i)
+}
+
+func panelViolation(b dashboard.Builder, p *sdk.Panel, rule string, severity Severity, message string) Violation {
+	return Violation{Dashboard: b.Internal().UID, Panel: p.Title, Rule: rule, Severity: severity, Message: message}
+}
+
+// panelError locates err like a Violation and adds the panel id, or the position in boardPanels if the panel has no id
+func panelError(b dashboard.Builder, index int, p *sdk.Panel, err error) error {
+	panel := fmt.Sprintf("id %d", p.ID)
+	if p.ID == 0 {
+		panel = fmt.Sprintf("#%d", index)
+	}
+	return fmt.Errorf("Error by %s / %s (panel %s): %w", b.Internal().UID, p.Title, panel, err)
+}
+
+// PanelsHaveDescription requires a description at every panel
+func PanelsHaveDescription(severity Severity) Validator {
+	return func(b dashboard.Builder) []Violation {
+		res := []Violation{}
+		for _, ref := range boardPanels(b.Internal()) {
+			if ref.Panel.Description == nil || strings.TrimSpace(*ref.Panel.Description) == "" {
+				res = append(res, panelViolation(b, ref.Panel, "panel-description", severity, "panel has no description"))
+			}
+		}
+		return res
+	}
+}
+
+// PanelsHaveDatasource requires a datasource at every panel with queries, either at the panel or at all its targets
+func PanelsHaveDatasource(severity Severity) Validator {
+	return func(b dashboard.Builder) []Violation {
+		res := []Violation{}
+		for _, ref := range boardPanels(b.Internal()) {
+			targets := ref.Panel.GetTargets()
+			if targets == nil || len(*targets) == 0 || ref.Panel.Datasource != nil {
+				continue
+			}
+			for i, t := range *targets {
+				if t.Datasource == nil {
+					res = append(res, panelViolation(b, ref.Panel, "panel-datasource", severity, fmt.Sprintf("target %s has no datasource", targetName(t, i))))
+				}
+			}
+		}
+		return res
+	}
+}
+
+// RequireTagPrefix requires at least one tag starting with prefix, e.g. "owner:"
+func RequireTagPrefix(prefix string, severity Severity) Validator {
+	return func(b dashboard.Builder) []Violation {
+		for _, t := range b.Internal().Tags {
+			if strings.HasPrefix(t, prefix) {
+				return nil
+			}
+		}
+		return []Violation{{Dashboard: b.Internal().UID, Rule: "tag-prefix", Severity: severity, Message: fmt.Sprintf("dashboard has no tag starting with %q", prefix)}}
+	}
+}
+
+// parseGrafanaDuration parses durations like 30s, 5m or 1d
+func parseGrafanaDuration(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		d, err := strconv.Atoi(days)
+		return time.Duration(d) * 24 * time.Hour, err
+	}
+	return time.ParseDuration(value)
+}
+
+// MinRefresh requires the auto refresh to be off or at least min
+func MinRefresh(min time.Duration, severity Severity) Validator {
+	return func(b dashboard.Builder) []Violation {
+		refresh := b.Internal().Refresh
+		if refresh == nil || refresh.Value == "" {
+			return nil
+		}
+		d, err := parseGrafanaDuration(refresh.Value)
+		if err != nil {
+			return []Violation{{Dashboard: b.Internal().UID, Rule: "min-refresh", Severity: severity, Message: fmt.Sprintf("invalid refresh %q", refresh.Value)}}
+		}
+		if d < min {
+			return []Violation{{Dashboard: b.Internal().UID, Rule: "min-refresh", Severity: severity, Message: fmt.Sprintf("refresh %s is below %s", refresh.Value, min)}}
+		}
+		return nil
+	}
+}
+
+// Validate runs all Validators and the grafana compatibility check on the dashboard
+func (r *Runner) Validate(b dashboard.Builder) []Violation {
+	return r.validate(b, r.compatibilityValidator())
+}
+
+func (r *Runner) validate(b dashboard.Builder, compatibility Validator) []Violation {
+	res := []Violation{}
+	if compatibility != nil {
+		res = append(res, compatibility(b)...)
+	}
+	for _, v := range r.Validators {
+		res = append(res, v(b)...)
+	}
+	return res
+}
+
+// countErrors returns the number of violations with SeverityError
+func countErrors(violations []Violation) int {
+	res := 0
+	for _, v := range violations {
+		if v.Severity == SeverityError {
+			res++
+		}
+	}
+	return res
+}
+
+func hasErrors(violations []Violation) bool {
+	return countErrors(violations) > 0
+}
+
+// validateBoards runs the Validators on every dashboard. The error lists all dashboards with SeverityError violations
+func (r *Runner) validateBoards(board []dashboard.Builder) ([][]Violation, error) {
+	res := make([][]Violation, 0, len(board))
+	err := errors.Join(nil)
+	compatibility := r.compatibilityValidator()
+	for _, b := range board {
+		violations := r.validate(b, compatibility)
+		if hasErrors(violations) {
+			err = errors.Join(err, fmt.Errorf("Dashboard %s violates policies", b.Internal().UID))
+		}
+		res = append(res, violations)
+	}
+	return res, err
+}
+
+func printViolations(results []DashboardResult) {
+	for _, res := range results {
+		for _, v := range res.Violations {
+			fmt.Fprintln(os.Stderr, v)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package grabanaclistarter
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/K-Phoen/grabana/dashboard"
+	"github.com/K-Phoen/grabana/row"
+	"github.com/K-Phoen/grabana/timeseries"
+	"github.com/K-Phoen/sdk"
+)
+
+func policyBoard(t *testing.T, options ...dashboard.Option) dashboard.Builder {
+	t.Helper()
+	b, err := dashboard.New("policy", append([]dashboard.Option{dashboard.UID("policy")}, options...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func panelRow(panels ...row.Option) dashboard.Option {
+	return dashboard.Row("r", panels...)
+}
+
+func TestValidators(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator Validator
+		board     []dashboard.Option
+		want      []string
+	}{
+		{
+			name:      "description missing",
+			validator: PanelsHaveDescription(SeverityError),
+			board:     []dashboard.Option{panelRow(row.WithTimeSeries("cpu"))},
+			want:      []string{"[error] policy / cpu: panel has no description (panel-description)"},
+		},
+		{
+			name:      "description set",
+			validator: PanelsHaveDescription(SeverityError),
+			board:     []dashboard.Option{panelRow(row.WithTimeSeries("cpu", timeseries.Description("load")))},
+		},
+		{
+			name:      "datasource missing",
+			validator: PanelsHaveDatasource(SeverityWarning),
+			board:     []dashboard.Option{panelRow(row.WithTimeSeries("cpu", timeseries.WithPrometheusTarget("up")))},
+			want:      []string{"[warning] policy / cpu: target #0 has no datasource (panel-datasource)"},
+		},
+		{
+			name:      "datasource at panel",
+			validator: PanelsHaveDatasource(SeverityWarning),
+			board:     []dashboard.Option{panelRow(row.WithTimeSeries("cpu", timeseries.DataSource("prom"), timeseries.WithPrometheusTarget("up")))},
+		},
+		{
+			name:      "panel without targets",
+			validator: PanelsHaveDatasource(SeverityWarning),
+			board:     []dashboard.Option{panelRow(row.WithTimeSeries("cpu"))},
+		},
+		{
+			name:      "tag prefix missing",
+			validator: RequireTagPrefix("owner:", SeverityError),
+			board:     []dashboard.Option{dashboard.Tags([]string{"team"})},
+			want:      []string{`[error] policy: dashboard has no tag starting with "owner:" (tag-prefix)`},
+		},
+		{
+			name:      "tag prefix set",
+			validator: RequireTagPrefix("owner:", SeverityError),
+			board:     []dashboard.Option{dashboard.Tags([]string{"team", "owner:sre"})},
+		},
+		{
+			name:      "refresh off",
+			validator: MinRefresh(time.Minute, SeverityError),
+		},
+		{
+			name:      "refresh too fast",
+			validator: MinRefresh(time.Minute, SeverityError),
+			board:     []dashboard.Option{dashboard.AutoRefresh("10s")},
+			want:      []string{"[error] policy: refresh 10s is below 1m0s (min-refresh)"},
+		},
+		{
+			name:      "refresh in days",
+			validator: MinRefresh(time.Minute, SeverityError),
+			board:     []dashboard.Option{dashboard.AutoRefresh("1d")},
+		},
+		{
+			name:      "refresh invalid",
+			validator: MinRefresh(time.Minute, SeverityInfo),
+			board:     []dashboard.Option{dashboard.AutoRefresh("soon")},
+			want:      []string{`[info] policy: invalid refresh "soon" (min-refresh)`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, v := range tt.validator(policyBoard(t, tt.board...)) {
+				got = append(got, v.String())
+			}
+			if tt.want == nil {
+				tt.want = []string{}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatorsSeeCollapsedRows(t *testing.T) {
+	b := policyBoard(t, panelRow(row.WithTimeSeries("visible", timeseries.Description("load"))))
+	collapsed := &sdk.Panel{}
+	if err := json.Unmarshal([]byte(`{"type":"row","title":"more","collapsed":true,"panels":[{"type":"graph","title":"hidden"}]}`), collapsed); err != nil {
+		t.Fatal(err)
+	}
+	b.Internal().Panels = append(b.Internal().Panels, collapsed)
+	got := []string{}
+	for _, v := range PanelsHaveDescription(SeverityError)(b) {
+		got = append(got, v.String())
+	}
+	want := []string{"[error] policy / hidden: panel has no description (panel-description)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestApplyBlockedByPolicy(t *testing.T) {
+	r := &Runner{
+		Dashboard: func(in CreatorInput) ([]dashboard.Builder, error) {
+			return []dashboard.Builder{policyBoard(t)}, nil
+		},
+		Validators: []Validator{RequireTagPrefix("owner:", SeverityError)},
+	}
+	results, err := r.Apply(context.Background(), ApplyOptions{})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(results) != 1 || results[0].Status != StatusBlocked || len(results[0].Violations) != 1 {
+		t.Errorf("got %+v, want one blocked dashboard with its violation", results)
+	}
+}
@@ -0,0 +1,259 @@
+package grabanaclistarter
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/K-Phoen/grabana/dashboard"
+	"github.com/urfave/cli/v2"
+)
+
+const CliSvgDir CliValues = "svg-dir"
+
+const (
+	// gridColumns is the width of the grafana grid
+	gridColumns = 24
+	// gridCellHeight is the height in px of one grid unit incl. margin, used to convert legacy row heights
+	gridCellHeight = 38
+	// defaultRowHeight is the height grafana uses for legacy rows without height
+	defaultRowHeight = 250
+)
+
+func previewFlags(appName string) []cli.Flag {
+	return append(append(creatorFlags(appName), filterFlags(appName)...),
+		&cli.StringFlag{
+			Name:    CliSvgDir,
+			EnvVars: []string{GetFlagEnvByFlagName(CliSvgDir, appName)},
+			Usage:   "directory to write one svg per dashboard to (default: no svg)",
+		},
+	)
+}
+
+// LayoutPanel is the position of a panel on the grafana grid
+type LayoutPanel struct {
+	Title string
+	Type  string
+	X     int
+	Y     int
+	W     int
+	H     int
+}
+
+func (p LayoutPanel) isRow() bool {
+	return p.Type == "row"
+}
+
+// gridHeight converts a legacy height like "250px" or 250 to grid units
+func gridHeight(height any, fallback int) int {
+	px := 0
+	switch h := height.(type) {
+	case float64:
+		px = int(h)
+	case string:
+		px, _ = strconv.Atoi(strings.TrimSuffix(h, "px"))
+	}
+	if px <= 0 {
+		return fallback
+	}
+	return int(math.Ceil(float64(px) / gridCellHeight))
+}
+
+// Layout computes the grid of the dashboard json. Panels with gridPos keep it, collapsed rows are one line without
+// their panels. Legacy rows are placed like grafana migrates them: the row title takes one line, panels are span*2
+// wide and wrap at the grid width
+func Layout(b dashboard.Builder) ([]LayoutPanel, error) {
+	board, err := parseLintBoard(b)
+	if err != nil {
+		return nil, err
+	}
+	return boardLayout(board), nil
+}
+
+func boardLayout(board lintBoard) []LayoutPanel {
+	res := []LayoutPanel{}
+	y := 0
+	add := func(p lintPanel) {
+		if p.GridPos == nil {
+			return
+		}
+		res = append(res, LayoutPanel{Title: p.Title, Type: p.Type, X: p.GridPos.X, Y: p.GridPos.Y, W: p.GridPos.W, H: p.GridPos.H})
+		y = max(y, p.GridPos.Y+p.GridPos.H)
+	}
+	// the panels of a collapsed row keep the gridPos of the expanded row, grafana only shows the row line
+	for _, p := range board.Panels {
+		add(p)
+	}
+	for _, row := range board.Rows {
+		if row.Title != "" || row.Collapse {
+			res = append(res, LayoutPanel{Title: row.Title, Type: "row", Y: y, W: gridColumns, H: 1})
+			y++
+		}
+		if row.Collapse {
+			continue
+		}
+		rowHeight := gridHeight(row.Height, (defaultRowHeight+gridCellHeight-1)/gridCellHeight)
+		x, lineHeight := 0, 0
+		for _, p := range row.Panels {
+			w := int(math.Floor(p.Span)) * 2
+			if w <= 0 || w > gridColumns {
+				w = gridColumns
+			}
+			h := gridHeight(p.Height, rowHeight)
+			if x+w > gridColumns {
+				x, y, lineHeight = 0, y+lineHeight, 0
+			}
+			res = append(res, LayoutPanel{Title: p.Title, Type: p.Type, X: x, Y: y, W: w, H: h})
+			x += w
+			lineHeight = max(lineHeight, h)
+		}
+		y += lineHeight
+	}
+	return res
+}
+
+// asciiColumnWidth is the number of characters of one grid column
+const asciiColumnWidth = 3
+
+type canvas [][]rune
+
+func newCanvas(width, height int) canvas {
+	c := make(canvas, height)
+	for i := range c {
+		c[i] = []rune(strings.Repeat(" ", width))
+	}
+	return c
+}
+
+func (c canvas) set(x, y int, r rune) {
+	if y >= 0 && y < len(c) && x >= 0 && x < len(c[y]) {
+		c[y][x] = r
+	}
+}
+
+// text writes s at x, y cut at maxLen
+func (c canvas) text(x, y, maxLen int, s string) {
+	for i, r := range []rune(s) {
+		if i >= maxLen {
+			return
+		}
+		c.set(x+i, y, r)
+	}
+}
+
+// RenderASCII writes the layout as wireframe. Every grid column is 3 characters wide, every grid unit one line
+func RenderASCII(w io.Writer, panels []LayoutPanel) error {
+	height := 0
+	for _, p := range panels {
+		height = max(height, p.Y+p.H)
+	}
+	c := newCanvas(gridColumns*asciiColumnWidth+1, height+1)
+	for _, p := range panels {
+		left, right := p.X*asciiColumnWidth, (p.X+p.W)*asciiColumnWidth
+		top, bottom := p.Y, p.Y+p.H
+		if p.isRow() {
+			for x := left; x <= right; x++ {
+				c.set(x, top, '=')
+			}
+			c.text(left+2, top, right-left-3, " "+p.Title+" ")
+			continue
+		}
+		for x := left; x <= right; x++ {
+			c.set(x, top, '-')
+			c.set(x, bottom, '-')
+		}
+		for y := top; y <= bottom; y++ {
+			c.set(left, y, '|')
+			c.set(right, y, '|')
+		}
+		for _, corner := range [][2]int{{left, top}, {right, top}, {left, bottom}, {right, bottom}} {
+			c.set(corner[0], corner[1], '+')
+		}
+		c.text(left+2, top, right-left-3, " "+p.Title+" ")
+		c.text(left+2, top+1, right-left-3, fmt.Sprintf("%s %dx%d", p.Type, p.W, p.H))
+	}
+	for _, line := range c {
+		if _, err := fmt.Fprintln(w, strings.TrimRight(string(line), " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const (
+	svgColumnWidth = 40
+	svgUnitHeight  = 30
+)
+
+// RenderSVG writes the layout as svg image
+func RenderSVG(w io.Writer, title string, panels []LayoutPanel) error {
+	height := 0
+	for _, p := range panels {
+		height = max(height, p.Y+p.H)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="12">`+"\n",
+		gridColumns*svgColumnWidth, height*svgUnitHeight)
+	fmt.Fprintf(&b, "<title>%s</title>\n", html.EscapeString(title))
+	for _, p := range panels {
+		x, y := p.X*svgColumnWidth, p.Y*svgUnitHeight
+		width, h := p.W*svgColumnWidth, p.H*svgUnitHeight
+		if p.isRow() {
+			fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="#d8d9da"/>`+"\n", x, y, width, h)
+			fmt.Fprintf(&b, `<text x="%d" y="%d" font-weight="bold">%s</text>`+"\n", x+6, y+h/2+4, html.EscapeString(p.Title))
+			continue
+		}
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" rx="3" fill="#f4f5f5" stroke="#8e8e8e"/>`+"\n", x+2, y+2, width-4, h-4)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-weight="bold">%s</text>`+"\n", x+8, y+18, html.EscapeString(p.Title))
+		fmt.Fprintf(&b, `<text x="%d" y="%d" fill="#6e6e6e">%s %dx%d</text>`+"\n", x+8, y+34, html.EscapeString(p.Type), p.W, p.H)
+	}
+	b.WriteString("</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeSVG(filename, title string, panels []LayoutPanel) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("Error creating %s: %w", filename, err)
+	}
+	defer f.Close()
+	return RenderSVG(f, title, panels)
+}
+
+func (r *Runner) previewAction(c *cli.Context) error {
+	board, err := r.Dashboards(r.CreatorInput(c), FilterFromValues(c))
+	if err != nil {
+		return err
+	}
+	svgDir := c.String(CliSvgDir)
+	if svgDir != "" {
+		if err := os.MkdirAll(svgDir, 0o755); err != nil {
+			return err
+		}
+	}
+	for _, b := range board {
+		panels, err := Layout(b)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s (%s)\n", b.Internal().Title, b.Internal().UID)
+		if err := RenderASCII(os.Stdout, panels); err != nil {
+			return err
+		}
+		fmt.Println()
+		if svgDir != "" {
+			filename := path.Join(svgDir, b.Internal().UID+".svg")
+			if err := writeSVG(filename, b.Internal().Title, panels); err != nil {
+				return err
+			}
+			r.logger().Info("Preview written", "uid", b.Internal().UID, "file", filename)
+		}
+	}
+	return nil
+}
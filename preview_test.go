@@ -0,0 +1,75 @@
+package grabanaclistarter
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/K-Phoen/grabana/dashboard"
+	"github.com/K-Phoen/grabana/row"
+	"github.com/K-Phoen/grabana/stat"
+	"github.com/K-Phoen/grabana/timeseries"
+)
+
+func previewBoard(t *testing.T) dashboard.Builder {
+	t.Helper()
+	b, err := dashboard.New("Preview",
+		dashboard.UID("preview"),
+		dashboard.Row("Overview",
+			row.WithStat("up", stat.Span(4), stat.Height("150px")),
+			row.WithTimeSeries("requests", timeseries.Span(8)),
+		),
+		dashboard.Row("Details",
+			row.Collapse(),
+			row.WithTimeSeries("hidden", timeseries.Span(12)),
+		),
+		dashboard.Row("Errors",
+			row.WithTimeSeries("errors", timeseries.Span(12), timeseries.Height("200px")),
+		),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestLayoutSkipsCollapsedPanels(t *testing.T) {
+	panels, err := Layout(previewBoard(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	details, errors := -1, -1
+	for _, p := range panels {
+		switch p.Title {
+		case "hidden":
+			t.Errorf("got panel %q of a collapsed row at %d,%d", p.Title, p.X, p.Y)
+		case "Details":
+			details = p.Y
+		case "Errors":
+			errors = p.Y
+		}
+	}
+	if details < 0 || errors != details+1 {
+		t.Errorf("got row Details at %d and Errors at %d, want Errors right below the collapsed row", details, errors)
+	}
+}
+
+func TestRenderPreview(t *testing.T) {
+	panels, err := Layout(previewBoard(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("ascii", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := RenderASCII(&buf, panels); err != nil {
+			t.Fatal(err)
+		}
+		assertGoldenFile(t, "preview.golden.txt", buf.Bytes())
+	})
+	t.Run("svg", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := RenderSVG(&buf, "Preview", panels); err != nil {
+			t.Fatal(err)
+		}
+		assertGoldenFile(t, "preview.golden.svg", buf.Bytes())
+	})
+}
@@ -0,0 +1,109 @@
+package grabanaclistarter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+const (
+	// placeholderDuration replaces variables used as range, subquery or offset
+	placeholderDuration = "5m"
+	// placeholderValue replaces variables used as label value or metric name
+	placeholderValue = "placeholder"
+)
+
+// isDurationContext reports if a variable between start and end of expr is used as a duration
+func isDurationContext(expr string, start, end int) bool {
+	before := strings.TrimRight(expr[:start], " ")
+	after := strings.TrimLeft(expr[end:], " ")
+	return strings.HasSuffix(before, "[") || strings.HasSuffix(before, ":") ||
+		strings.HasSuffix(before, "offset") ||
+		strings.HasPrefix(after, "]") || strings.HasPrefix(after, ":")
+}
+
+// promqlPlaceholders replaces all grafana variables of expr so the prometheus parser accepts it
+func promqlPlaceholders(expr string) string {
+	var b strings.Builder
+	last := 0
+	var quote byte
+	for _, m := range variableMatches(expr) {
+		for i := last; i < m.Start; i++ {
+			switch {
+			case quote == 0 && (expr[i] == '"' || expr[i] == '\'' || expr[i] == '`'):
+				quote = expr[i]
+			case quote != 0 && expr[i] == '\\':
+				i++
+			case quote != 0 && expr[i] == quote:
+				quote = 0
+			}
+		}
+		b.WriteString(expr[last:m.Start])
+		if quote == 0 && isDurationContext(expr, m.Start, m.End) {
+			b.WriteString(placeholderDuration)
+		} else {
+			b.WriteString(placeholderValue)
+		}
+		last = m.End
+	}
+	b.WriteString(expr[last:])
+	return b.String()
+}
+
+// isPrometheusTarget reports if the target holds a PromQL expression. Targets of other datasources using expr
+// (like loki) are skipped if their datasource type is known
+func isPrometheusTarget(panel lintPanel, target map[string]any) bool {
+	if _, ok := target["expr"].(string); !ok {
+		return false
+	}
+	for _, ds := range []any{target["datasource"], panel.Datasource} {
+		if ref, ok := ds.(map[string]any); ok {
+			if t, _ := ref["type"].(string); t != "" {
+				return t == "prometheus"
+			}
+		}
+	}
+	return true
+}
+
+func lintPromQL(board lintBoard) []Violation {
+	res := []Violation{}
+	for _, p := range board.panels() {
+		for i, t := range p.Targets {
+			if !isPrometheusTarget(p, t) {
+				continue
+			}
+			name, _ := t["refId"].(string)
+			if name == "" {
+				name = fmt.Sprintf("#%d", i)
+			}
+			expr := t["expr"].(string)
+			if strings.TrimSpace(expr) == "" {
+				res = append(res, lintViolation(board, p.Title, "promql-syntax", fmt.Sprintf("target %s has an empty expression", name)))
+				continue
+			}
+			if _, err := parser.ParseExpr(promqlPlaceholders(expr)); err != nil {
+				res = append(res, lintViolation(board, p.Title, "promql-syntax", fmt.Sprintf("target %s: %s", name, err)))
+			}
+		}
+	}
+	return res
+}
+
+// referencedMetrics returns the metric names selected by expr in order of appearance. An expression the parser
+// rejects is returned as is, so a plain record name still matches
+func referencedMetrics(expr string) []string {
+	node, err := parser.ParseExpr(promqlPlaceholders(expr))
+	if err != nil {
+		return []string{expr}
+	}
+	res := []string{}
+	parser.Inspect(node, func(n parser.Node, _ []parser.Node) error {
+		if vs, ok := n.(*parser.VectorSelector); ok && vs.Name != "" {
+			res = appendUnique(res, vs.Name)
+		}
+		return nil
+	})
+	return res
+}
@@ -0,0 +1,69 @@
+package grabanaclistarter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+func TestUsedVariables(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: `up{job="$job"}`, want: []string{"job"}},
+		{query: `rate(x{instance=~"${instance:regex}"}[$__rate_interval])`, want: []string{"instance", "__rate_interval"}},
+		{query: `sum(x{env="[[env]]"})`, want: []string{"env"}},
+		{query: `label_replace(up, "host", "$1", "instance", "(.*):.*")`, want: []string{}},
+		{query: `label_replace(up{job="$job"}, "host", "$host", "instance", "(?P<host>.*):.*")`, want: []string{"job"}},
+		{query: `label_join(up, "id", "${sep}", "a", "b")`, want: []string{}},
+		{query: `up offset ${1}`, want: []string{}},
+		{query: `SELECT * FROM t WHERE host = '$host'`, want: []string{"host"}},
+	}
+	for _, tt := range tests {
+		if got := usedVariables(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("usedVariables(%s) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestPromqlPlaceholders(t *testing.T) {
+	tests := []struct {
+		expr, want string
+	}{
+		{expr: `up{job="$job"}`, want: `up{job="placeholder"}`},
+		{expr: `rate(x[$__rate_interval])`, want: `rate(x[5m])`},
+		{expr: `rate(x[${interval}:$step])`, want: `rate(x[5m:5m])`},
+		{expr: `up offset $shift`, want: `up offset 5m`},
+		{expr: `$metric{job="a"}`, want: `placeholder{job="a"}`},
+		{expr: `x{le="[$le]"}`, want: `x{le="[placeholder]"}`},
+		{expr: `label_replace(up{job="$job"}, "host", "$1", "instance", "(.*):.*")`, want: `label_replace(up{job="placeholder"}, "host", "$1", "instance", "(.*):.*")`},
+	}
+	for _, tt := range tests {
+		got := promqlPlaceholders(tt.expr)
+		if got != tt.want {
+			t.Errorf("promqlPlaceholders(%s) = %s, want %s", tt.expr, got, tt.want)
+		}
+		if _, err := parser.ParseExpr(got); err != nil {
+			t.Errorf("promqlPlaceholders(%s) does not parse: %v", tt.expr, err)
+		}
+	}
+}
+
+func TestReferencedMetrics(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{expr: `job:requests:rate5m`, want: []string{"job:requests:rate5m"}},
+		{expr: `sum(job:errors:rate5m{env="$env"}) / sum(job:requests:rate5m) + sum(job:errors:rate5m)`, want: []string{"job:errors:rate5m", "job:requests:rate5m"}},
+		{expr: `rate(http_requests_total[$__rate_interval])`, want: []string{"http_requests_total"}},
+		{expr: `sum(`, want: []string{"sum("}},
+	}
+	for _, tt := range tests {
+		if got := referencedMetrics(tt.expr); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("referencedMetrics(%s) = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+}
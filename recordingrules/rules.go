@@ -5,10 +5,11 @@ package recordingrules
 import (
 	"crypto/md5"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/K-Phoen/grabana/gauge"
@@ -28,8 +29,9 @@ type RecordingRule struct {
 }
 
 type RecodingMap struct {
-	data  map[[16]byte]RecordingRule
-	debug bool
+	data   map[[16]byte]RecordingRule
+	debug  bool
+	logger *slog.Logger
 }
 
 // NewRecordingMap if debug show queries instant of recording rule
@@ -40,6 +42,18 @@ func NewRecordingMap(debug bool) RecodingMap {
 	}
 }
 
+// SetLogger replaces the logger, default is slog.Default()
+func (m *RecodingMap) SetLogger(logger *slog.Logger) {
+	m.logger = logger
+}
+
+func (m *RecodingMap) log() *slog.Logger {
+	if m.logger == nil {
+		return slog.Default()
+	}
+	return m.logger
+}
+
 type PrometheusGroups struct {
 	Groups []PrometheusGroup `yaml:"groups"`
 }
@@ -131,7 +145,7 @@ func (m *RecodingMap) record(name, expr string) [16]byte {
 			Expr: escapedExpr,
 		}
 	} else {
-		log.Printf("Same expr found. Rule found under name: %s. will use this also for %s \n", m.data[hash].Name, name)
+		m.log().Info("Same expr found, reusing recording rule", "rule", m.data[hash].Name, "for", name)
 	}
 	return hash
 }
@@ -313,3 +327,13 @@ func (m *RecodingMap) WithTimeSeries(recordName, query string, options ...promet
 // 	}
 
 // }
+
+// Rules returns all recorded rules sorted by name
+func (m *RecodingMap) Rules() []RecordingRule {
+	res := make([]RecordingRule, 0, len(m.data))
+	for _, v := range m.data {
+		res = append(res, v)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
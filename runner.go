@@ -0,0 +1,297 @@
+package grabanaclistarter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/K-Phoen/grabana"
+	"github.com/K-Phoen/grabana/dashboard"
+	"github.com/cryptvault-cloud/helper"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+// Values gives read access to flag values. *cli.Context implements it
+type Values interface {
+	String(name string) string
+	StringSlice(name string) []string
+	Bool(name string) bool
+	Int(name string) int
+	Float64(name string) float64
+	Duration(name string) time.Duration
+	IsSet(name string) bool
+}
+
+// CreatorInput is everything a Creator gets to build the dashboards
+type CreatorInput struct {
+	FolderName  string
+	Environment Environment
+	// Flags may be nil if the Runner is used as library
+	Flags Values
+}
+
+// Creator builds the dashboards independent of the cli
+type Creator func(in CreatorInput) ([]dashboard.Builder, error)
+
+// WithCreator sets a Creator which is usable from cli and library
+func WithCreator(d Creator) Option {
+	return func(runner *Runner, app *cli.App) error {
+		if runner.Dashboard != nil {
+			return fmt.Errorf("Dashboard already set")
+		}
+		runner.Dashboard = d
+		return nil
+	}
+}
+
+// cliCreator adapts a DashboardCreator. It only works if the input Flags are a *cli.Context
+func cliCreator(d DashboardCreator) Creator {
+	return func(in CreatorInput) ([]dashboard.Builder, error) {
+		c, ok := in.Flags.(*cli.Context)
+		if !ok {
+			return nil, fmt.Errorf("DashboardCreator needs a *cli.Context, use WithCreator to run without cli")
+		}
+		return d(in.FolderName, c)
+	}
+}
+
+func NewRunner(server string, client *grabana.Client, creator Creator) *Runner {
+	return &Runner{
+		Server:    server,
+		Client:    client,
+		Ctx:       context.Background(),
+		Dashboard: creator,
+	}
+}
+
+type ResultStatus string
+
+const (
+	StatusDone    ResultStatus = "done"
+	StatusFailed  ResultStatus = "failed"
+	StatusSkipped ResultStatus = "skipped"
+	// StatusBlocked is used for all dashboards if a Validator reports an error
+	StatusBlocked ResultStatus = "blocked"
+	// StatusPlanned is used by a dry run, StatusMissing for dashboards not found in grafana
+	StatusPlanned ResultStatus = "planned"
+	StatusMissing ResultStatus = "missing"
+)
+
+// DashboardResult is the outcome of one dashboard
+type DashboardResult struct {
+	UID    string
+	Title  string
+	Folder string
+	// Action is apply, destroy or plan
+	Action string
+	URL    string
+	// Version is the grafana version after apply (needs Runner.HTTPClient)
+	Version int
+	// Status is StatusSkipped for dashboards not processed because the context was done and StatusBlocked
+	// if apply was stopped by policy violations
+	Status ResultStatus
+	// Model is the dashboard json (set by Plan)
+	Model json.RawMessage
+	// Violations of the Validators (set by Apply and Plan)
+	Violations []Violation
+	Err        error
+}
+
+type ApplyOptions struct {
+	CreatorInput
+	Filter Filter
+}
+
+type DestroyOptions struct {
+	CreatorInput
+	Filter Filter
+	// DryRun only looks up the dashboards (see DestroyPlan)
+	DryRun bool
+	// Missing are uids known to not exist (e.g. by DestroyPlan), they are StatusMissing without a request
+	Missing []string
+}
+
+type PlanOptions struct {
+	CreatorInput
+	Filter Filter
+}
+
+type ExportOptions struct {
+	CreatorInput
+	Filter Filter
+}
+
+// Build runs the Creator and applies the Mutators
+func (r *Runner) Build(in CreatorInput) ([]dashboard.Builder, error) {
+	if r.Dashboard == nil {
+		return nil, fmt.Errorf("No dashboard creator set")
+	}
+	board, err := r.Dashboard(in)
+	if err != nil {
+		return nil, err
+	}
+	return board, r.mutate(board)
+}
+
+func newResult(b dashboard.Builder, folder, action string) DashboardResult {
+	return DashboardResult{
+		UID:    b.Internal().UID,
+		Title:  b.Internal().Title,
+		Folder: folder,
+		Action: action,
+		Status: StatusDone,
+	}
+}
+
+// skipRemaining marks all not processed dashboards as skipped once ctx is done
+func skipRemaining(ctx context.Context, results []DashboardResult, board []dashboard.Builder, folder, action string) ([]DashboardResult, error) {
+	processed := len(results)
+	for _, b := range board[processed:] {
+		res := newResult(b, folder, action)
+		res.Status = StatusSkipped
+		results = append(results, res)
+	}
+	return results, fmt.Errorf("Stopped after %d of %d dashboards: %w", processed, len(board), ctx.Err())
+}
+
+// Apply uploads all dashboards into the folder. The error joins all failed dashboards.
+// Nothing is applied if a Validator reports an error, all dashboards are StatusBlocked then.
+// Once ctx is done the remaining dashboards are returned as StatusSkipped, the request in flight is finished
+func (r *Runner) Apply(ctx context.Context, opts ApplyOptions) ([]DashboardResult, error) {
+	board, err := r.Dashboards(opts.CreatorInput, opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+	r.detectGrafanaVersion(ctx)
+	violations, err := r.validateBoards(board)
+	if err != nil {
+		results := make([]DashboardResult, 0, len(board))
+		for i, b := range board {
+			res := newResult(b, opts.FolderName, "apply")
+			res.Status = StatusBlocked
+			res.Violations = violations[i]
+			results = append(results, res)
+		}
+		return results, err
+	}
+	folder, err := r.Client.FindOrCreateFolder(ctx, opts.FolderName)
+	if err != nil {
+		return nil, fmt.Errorf("Could not find or create folder: %w", err)
+	}
+	results := make([]DashboardResult, 0, len(board))
+	err = errors.Join(nil)
+	for i, b := range board {
+		if ctx.Err() != nil {
+			results, ctxErr := skipRemaining(ctx, results, board, folder.Title, "apply")
+			return results, errors.Join(err, ctxErr)
+		}
+		res := newResult(b, folder.Title, "apply")
+		res.Violations = violations[i]
+		reqCtx, cancel := r.requestContext(ctx)
+		dash, tmpErr := r.Client.UpsertDashboard(reqCtx, folder, b)
+		if tmpErr != nil {
+			res.Status = StatusFailed
+			res.Err = fmt.Errorf("Could not create dashboard: %w", tmpErr)
+			err = errors.Join(err, res.Err)
+		} else {
+			res.URL = r.Server + dash.URL
+			if r.HTTPClient != nil {
+				if live, liveErr := r.LiveDashboard(reqCtx, dash.UID); liveErr == nil {
+					res.Version = live.Version()
+				}
+			}
+		}
+		cancel()
+		results = append(results, res)
+	}
+	return results, err
+}
+
+// Destroy removes all dashboards by UID, not existing ones are StatusMissing. Once ctx is done the remaining dashboards are returned as StatusSkipped,
+// the request in flight is finished
+func (r *Runner) Destroy(ctx context.Context, opts DestroyOptions) ([]DashboardResult, error) {
+	if opts.DryRun {
+		return r.DestroyPlan(ctx, opts)
+	}
+	board, err := r.Dashboards(opts.CreatorInput, opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]DashboardResult, 0, len(board))
+	err = errors.Join(nil)
+	for _, b := range board {
+		if ctx.Err() != nil {
+			results, ctxErr := skipRemaining(ctx, results, board, opts.FolderName, "destroy")
+			return results, errors.Join(err, ctxErr)
+		}
+		res := newResult(b, opts.FolderName, "destroy")
+		if helper.Includes(opts.Missing, func(uid string) bool { return uid == res.UID }) {
+			res.Status = StatusMissing
+			results = append(results, res)
+			continue
+		}
+		reqCtx, cancel := r.requestContext(ctx)
+		tmpErr := r.Client.DeleteDashboard(reqCtx, b.Internal().UID)
+		cancel()
+		switch {
+		case isNotFound(tmpErr):
+			res.Status = StatusMissing
+		case tmpErr != nil:
+			res.Status = StatusFailed
+			res.Err = fmt.Errorf("Error by %s: %w", b.Internal().UID, tmpErr)
+			err = errors.Join(err, res.Err)
+		}
+		results = append(results, res)
+	}
+	return results, err
+}
+
+// Plan renders the json of all dashboards and runs the Validators without talking to grafana. The
+// compatibility check only runs with an explicit GrafanaVersion
+func (r *Runner) Plan(ctx context.Context, opts PlanOptions) ([]DashboardResult, error) {
+	board, err := r.Dashboards(opts.CreatorInput, opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+	violations, _ := r.validateBoards(board)
+	results := make([]DashboardResult, 0, len(board))
+	err = errors.Join(nil)
+	for i, b := range board {
+		res := newResult(b, opts.FolderName, "plan")
+		res.Violations = violations[i]
+		model, tmpErr := b.MarshalIndentJSON()
+		if tmpErr != nil {
+			res.Status = StatusFailed
+			res.Err = fmt.Errorf("Error by %s: %w", b.Internal().UID, tmpErr)
+			err = errors.Join(err, res.Err)
+		}
+		res.Model = model
+		results = append(results, res)
+	}
+	return results, err
+}
+
+// WriteYaml writes all dashboards as yaml list of their json model
+func (r *Runner) WriteYaml(w io.Writer, opts ExportOptions) error {
+	board, err := r.Dashboards(opts.CreatorInput, opts.Filter)
+	if err != nil {
+		return err
+	}
+	models := make([]any, 0, len(board))
+	for _, b := range board {
+		content, err := b.MarshalJSON()
+		if err != nil {
+			return fmt.Errorf("Error by %s: %w", b.Internal().UID, err)
+		}
+		var model any
+		if err := yaml.Unmarshal(content, &model); err != nil {
+			return fmt.Errorf("Error by %s: %w", b.Internal().UID, err)
+		}
+		models = append(models, model)
+	}
+	return yaml.NewEncoder(w).Encode(models)
+}
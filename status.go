@@ -0,0 +1,233 @@
+package grabanaclistarter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/K-Phoen/grabana/dashboard"
+	"github.com/urfave/cli/v2"
+)
+
+type SyncState string
+
+const (
+	StateInSync    SyncState = "in-sync"
+	StateOutOfSync SyncState = "out-of-sync"
+	StateModified  SyncState = "modified"
+	StateMissing   SyncState = "missing"
+	StateError     SyncState = "error"
+)
+
+// DashboardStatus compares one dashboard of the Creator with grafana
+type DashboardStatus struct {
+	UID     string    `json:"uid" yaml:"uid"`
+	Title   string    `json:"title" yaml:"title"`
+	State   SyncState `json:"state" yaml:"state"`
+	Folder  string    `json:"folder,omitempty" yaml:"folder,omitempty"`
+	Version int       `json:"version,omitempty" yaml:"version,omitempty"`
+	URL     string    `json:"url,omitempty" yaml:"url,omitempty"`
+	// UpdatedBy is the grafana user of the last change
+	UpdatedBy string `json:"updatedBy,omitempty" yaml:"updatedBy,omitempty"`
+	// Drift lists what differs from code: model and/or folder
+	Drift []string `json:"drift,omitempty" yaml:"drift,omitempty"`
+	Error string   `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+type StatusOptions struct {
+	CreatorInput
+	Filter Filter
+}
+
+// ignoredStatusKeys are set by grafana on every save
+var ignoredStatusKeys = []string{"id", "version", "iteration", "schemaVersion"}
+
+// removePanelIDs deletes the id of all panels, including the ones of rows. The sdk counts panel ids
+// process wide, so they differ every time the dashboards are built
+func removePanelIDs(panels any) {
+	list, ok := panels.([]any)
+	if !ok {
+		return
+	}
+	for _, p := range list {
+		if panel, ok := p.(map[string]any); ok {
+			delete(panel, "id")
+			removePanelIDs(panel["panels"])
+		}
+	}
+}
+
+// sortVariableOptions sorts the options of all variables by value. grabana fills the options of custom variables
+// from a map, so their order changes every time the dashboards are built
+func sortVariableOptions(model map[string]any) {
+	templating, _ := model["templating"].(map[string]any)
+	list, _ := templating["list"].([]any)
+	for _, v := range list {
+		variable, _ := v.(map[string]any)
+		options, _ := variable["options"].([]any)
+		sort.SliceStable(options, func(i, j int) bool {
+			return optionKey(options[i]) < optionKey(options[j])
+		})
+	}
+}
+
+func optionKey(option any) string {
+	o, _ := option.(map[string]any)
+	return fmt.Sprint(o["value"], "\x00", o["text"])
+}
+
+// containsModel reports whether every value of code is equal in live. Keys only known by grafana are ignored
+func containsModel(code, live any) bool {
+	switch c := code.(type) {
+	case map[string]any:
+		l, ok := live.(map[string]any)
+		if !ok {
+			return false
+		}
+		for k, v := range c {
+			if v == nil {
+				continue
+			}
+			if !containsModel(v, l[k]) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		l, ok := live.([]any)
+		if !ok || len(l) != len(c) {
+			return len(c) == 0 && live == nil
+		}
+		for i := range c {
+			if !containsModel(c[i], l[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(code, live)
+}
+
+// NormalizedModel returns the json model of the dashboard without its id and the panel ids, which depend on
+// the build order, and with sorted variable options. It is the base of the status comparison and of the golden files of grabanatest
+func NormalizedModel(b dashboard.Builder) (map[string]any, error) {
+	content, err := b.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	model := map[string]any{}
+	if err := json.Unmarshal(content, &model); err != nil {
+		return nil, err
+	}
+	delete(model, "id")
+	removePanelIDs(model["panels"])
+	if rows, ok := model["rows"].([]any); ok {
+		for _, row := range rows {
+			if r, ok := row.(map[string]any); ok {
+				removePanelIDs(r["panels"])
+			}
+		}
+	}
+	sortVariableOptions(model)
+	return model, nil
+}
+
+func codeModel(b dashboard.Builder) (map[string]any, error) {
+	model, err := NormalizedModel(b)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range ignoredStatusKeys {
+		delete(model, k)
+	}
+	return model, nil
+}
+
+// currentUser returns the login used by the Runner, empty if unknown (e.g. legacy api keys)
+func (r *Runner) currentUser(ctx context.Context) string {
+	user := struct {
+		Login string `json:"login"`
+	}{}
+	if err := r.grafanaRequest(ctx, http.MethodGet, "/api/user", nil, &user); err != nil {
+		r.logger().Debug("Could not resolve current grafana user", "error", err)
+		return ""
+	}
+	return user.Login
+}
+
+// Status compares every dashboard of the Creator and its folder with the live state.
+// Dashboards differing from code are StateModified if the last change was made by another user than the Runner's one.
+// Legacy api keys have no user, with them every difference is StateOutOfSync
+func (r *Runner) Status(ctx context.Context, opts StatusOptions) ([]DashboardStatus, error) {
+	board, err := r.Dashboards(opts.CreatorInput, opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+	user := r.currentUser(ctx)
+	if user == "" {
+		r.logger().Warn("Grafana user unknown (legacy api key?), changes made in grafana are reported as out-of-sync")
+	}
+	res := make([]DashboardStatus, 0, len(board))
+	err = errors.Join(nil)
+	for _, b := range board {
+		if ctx.Err() != nil {
+			return res, errors.Join(err, ctx.Err())
+		}
+		status := DashboardStatus{UID: b.Internal().UID, Title: b.Internal().Title}
+		live, tmpErr := r.LiveDashboard(ctx, status.UID)
+		switch {
+		case isNotFound(tmpErr):
+			status.State = StateMissing
+		case tmpErr != nil:
+			status.State = StateError
+			status.Error = tmpErr.Error()
+			err = errors.Join(err, fmt.Errorf("Error by %s: %w", status.UID, tmpErr))
+		default:
+			status.Folder = live.Meta.FolderTitle
+			status.Version = live.Version()
+			status.URL = r.Server + live.Meta.URL
+			status.UpdatedBy = live.Meta.UpdatedBy
+			model, tmpErr := codeModel(b)
+			if tmpErr != nil {
+				return res, fmt.Errorf("Error by %s: %w", status.UID, tmpErr)
+			}
+			sortVariableOptions(live.Model)
+			if !containsModel(model, live.Model) {
+				status.Drift = append(status.Drift, "model")
+			}
+			if opts.FolderName != "" && live.Meta.FolderTitle != opts.FolderName {
+				status.Drift = append(status.Drift, "folder")
+			}
+			switch {
+			case len(status.Drift) == 0:
+				status.State = StateInSync
+			case user != "" && live.Meta.UpdatedBy != "" && live.Meta.UpdatedBy != user:
+				status.State = StateModified
+			default:
+				status.State = StateOutOfSync
+			}
+		}
+		res = append(res, status)
+	}
+	return res, err
+}
+
+func (r *Runner) statusAction(c *cli.Context) error {
+	status, err := r.Status(r.Ctx, StatusOptions{CreatorInput: r.CreatorInput(c), Filter: FilterFromValues(c)})
+	if format := c.String(CliOutput); format != OutputText {
+		return errors.Join(err, writeStructured(os.Stdout, format, status))
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "UID\tTITLE\tSTATE\tDRIFT\tFOLDER\tVERSION\tUPDATED BY\tURL")
+	for _, s := range status {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\n", s.UID, s.Title, s.State, strings.Join(s.Drift, ","), s.Folder, s.Version, s.UpdatedBy, s.URL)
+	}
+	return errors.Join(err, w.Flush())
+}
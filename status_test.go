@@ -0,0 +1,63 @@
+package grabanaclistarter
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/K-Phoen/grabana/dashboard"
+	"github.com/K-Phoen/grabana/row"
+	"github.com/K-Phoen/grabana/text"
+	"github.com/K-Phoen/grabana/timeseries"
+)
+
+func statusBoard(t *testing.T, title string) dashboard.Builder {
+	t.Helper()
+	b, err := dashboard.New("status", dashboard.UID("status"),
+		dashboard.Row("r",
+			row.WithTimeSeries(title, timeseries.WithPrometheusTarget("up")),
+			row.WithText("notes", text.Markdown("hello")),
+		))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// liveModel is the model as grafana returns it: with ids and the version of the last save
+func liveModel(t *testing.T, b dashboard.Builder) map[string]any {
+	t.Helper()
+	content, err := b.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := map[string]any{}
+	if err := json.Unmarshal(content, &model); err != nil {
+		t.Fatal(err)
+	}
+	model["id"], model["version"] = 12, 3
+	return model
+}
+
+func TestContainsModel(t *testing.T) {
+	live := liveModel(t, statusBoard(t, "requests"))
+	tests := []struct {
+		name string
+		code dashboard.Builder
+		want bool
+	}{
+		// the sdk assigns other panel ids on every build
+		{name: "rebuilt", code: statusBoard(t, "requests"), want: true},
+		{name: "changed title", code: statusBoard(t, "errors"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := codeModel(tt.code)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := containsModel(code, live); got != tt.want {
+				t.Errorf("containsModel = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package grabanaclistarter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/K-Phoen/grabana/dashboard"
+	"github.com/urfave/cli/v2"
+)
+
+// BuildInfo describes the binary build. Set it through the App* Options (e.g. from -ldflags values)
+type BuildInfo struct {
+	Version string
+	Commit  string
+	Date    string
+}
+
+func AppName(name string) Option {
+	return func(runner *Runner, app *cli.App) error {
+		app.Name = name
+		return nil
+	}
+}
+
+func AppUsage(usage string) Option {
+	return func(runner *Runner, app *cli.App) error {
+		app.Usage = usage
+		return nil
+	}
+}
+
+func AppVersion(version string) Option {
+	return func(runner *Runner, app *cli.App) error {
+		app.Version = version
+		runner.BuildInfo.Version = version
+		return nil
+	}
+}
+
+func AppBuildInfo(commit, date string) Option {
+	return func(runner *Runner, app *cli.App) error {
+		runner.BuildInfo.Commit = commit
+		runner.BuildInfo.Date = date
+		return nil
+	}
+}
+
+// moduleVersion returns the version of the given module linked into the binary
+func moduleVersion(module string) string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == module {
+			if dep.Replace != nil {
+				return fmt.Sprintf("%s => %s %s", dep.Version, dep.Replace.Path, dep.Replace.Version)
+			}
+			return dep.Version
+		}
+	}
+	return "unknown"
+}
+
+// DashboardsHash returns a sha256 over the NormalizedModel of all given dashboards in order, so rebuilding the
+// same dashboards gives the same hash
+func DashboardsHash(boards []dashboard.Builder) (string, error) {
+	h := sha256.New()
+	for _, b := range boards {
+		model, err := NormalizedModel(b)
+		if err != nil {
+			return "", fmt.Errorf("Error by %s: %w", b.Internal().UID, err)
+		}
+		content, err := json.Marshal(model)
+		if err != nil {
+			return "", fmt.Errorf("Error by %s: %w", b.Internal().UID, err)
+		}
+		h.Write(content)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (r *Runner) PrintVersion(c *cli.Context) error {
+	version := r.BuildInfo.Version
+	if version == "" {
+		version = "dev"
+	}
+	fmt.Printf("%s version: %s\n", c.App.Name, version)
+	if r.BuildInfo.Commit != "" {
+		fmt.Printf("\tCommit: %s\n", r.BuildInfo.Commit)
+	}
+	if r.BuildInfo.Date != "" {
+		fmt.Printf("\tBuild date: %s\n", r.BuildInfo.Date)
+	}
+	fmt.Printf("\tgrabana: %s\n", moduleVersion("github.com/K-Phoen/grabana"))
+	fmt.Printf("\tsdk: %s\n", moduleVersion("github.com/K-Phoen/sdk"))
+	if r.Dashboard == nil {
+		return nil
+	}
+	board, err := r.Build(r.CreatorInput(c))
+	if err != nil {
+		return err
+	}
+	hash, err := DashboardsHash(board)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\tDashboards: %d\n", len(board))
+	fmt.Printf("\tDashboards hash: %s\n", hash)
+	return nil
+}
@@ -0,0 +1,91 @@
+package grabanaclistarter
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/K-Phoen/grabana/dashboard"
+	"github.com/K-Phoen/grabana/row"
+	"github.com/K-Phoen/grabana/timeseries"
+	"github.com/K-Phoen/grabana/variable/custom"
+)
+
+func versionBoards(t *testing.T, titles ...string) []dashboard.Builder {
+	t.Helper()
+	res := []dashboard.Builder{}
+	for _, title := range titles {
+		b, err := dashboard.New(title,
+			dashboard.UID(strings.ToLower(title)),
+			dashboard.VariableAsCustom("env", custom.Values(custom.ValuesMap{"prod": "prod", "stage": "stage", "dev": "dev"}), custom.Default("prod")),
+			dashboard.Row("r", row.WithTimeSeries("requests", timeseries.WithPrometheusTarget(`rate(http_requests_total[5m])`))),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, b)
+	}
+	return res
+}
+
+func dashboardsHash(t *testing.T, boards []dashboard.Builder) string {
+	t.Helper()
+	hash, err := DashboardsHash(boards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestDashboardsHash(t *testing.T) {
+	hash := dashboardsHash(t, versionBoards(t, "API", "Web"))
+	// a rebuild gives new panel ids and another order of the variable options but the same hash
+	if rebuilt := dashboardsHash(t, versionBoards(t, "API", "Web")); rebuilt != hash {
+		t.Errorf("got hash %s for the rebuilt dashboards, want %s", rebuilt, hash)
+	}
+	for name, other := range map[string][]dashboard.Builder{
+		"changed title": versionBoards(t, "API", "Frontend"),
+		"other order":   versionBoards(t, "Web", "API"),
+		"less":          versionBoards(t, "API"),
+	} {
+		if got := dashboardsHash(t, other); got == hash {
+			t.Errorf("%s: got the hash of the original dashboards", name)
+		}
+	}
+}
+
+func TestPrintVersion(t *testing.T) {
+	r := &Runner{
+		BuildInfo: BuildInfo{Version: "1.2.3", Commit: "abc123", Date: "2024-05-01"},
+		Dashboard: func(in CreatorInput) ([]dashboard.Builder, error) {
+			return versionBoards(t, "API"), nil
+		},
+	}
+	c := flagContext(t, creatorFlags("app"))
+	c.App.Name = "app"
+	var err error
+	got := captureOutput(t, &os.Stdout, func() { err = r.PrintVersion(c) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"app version: 1.2.3\n",
+		"\tCommit: abc123\n",
+		"\tBuild date: 2024-05-01\n",
+		"\tDashboards: 1\n",
+		"\tDashboards hash: " + dashboardsHash(t, versionBoards(t, "API")) + "\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output %q does not contain %q", got, want)
+		}
+	}
+
+	r = &Runner{}
+	got = captureOutput(t, &os.Stdout, func() { err = r.PrintVersion(c) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got, "app version: dev\n") || strings.Contains(got, "Commit") || strings.Contains(got, "Dashboards") {
+		t.Errorf("got output %q, want the dev version without build info and dashboards", got)
+	}
+}